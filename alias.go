@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// ValidateAlias checks that an alias only has the keys that apply to it. An
+// alias has no definition of its own.
+func (service *Service) ValidateAlias() error {
+	if service.Alias == "" {
+		if service.Deprecated != "" {
+			return fmt.Errorf("deprecated can only be used with alias")
+		}
+
+		return nil
+	}
+
+	if keys := service.nonZeroKeysExcept("alias", "deprecated"); len(keys) > 0 {
+		return fmt.Errorf("alias cannot be used with %s", keys[0])
+	}
+
+	return nil
+}
+
+// aliasDiagnostics returns a problem if the service that an alias refers to
+// does not exist.
+func (service *Service) aliasDiagnostics(serviceName string, services Services) Diagnostics {
+	if service.Alias == "" {
+		return nil
+	}
+
+	var err error
+	target := services[service.Alias]
+	switch {
+	case target == nil:
+		err = unknownNameError("service", service.Alias, services.ServiceNames())
+
+	case target.Alias != "":
+		err = fmt.Errorf("cannot alias an alias: %s", service.Alias)
+
+	case target.Decorates != "":
+		err = fmt.Errorf("cannot alias a decorator: %s", service.Alias)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	return Diagnostics{{
+		Pos:     service.Position("alias"),
+		Service: serviceName,
+		Err:     err,
+	}}
+}
+
+// astDeprecated is the doc comment for the getters of an alias.
+func (service *Service) astDeprecated() *ast.CommentGroup {
+	if service.Deprecated == "" {
+		return nil
+	}
+
+	return &ast.CommentGroup{
+		List: []*ast.Comment{{Text: "// Deprecated: " + service.Deprecated}},
+	}
+}
+
+// astAliasGetters creates the getters for an alias. They call the getters of
+// the service that the alias refers to, so that replacing that service on the
+// Container also affects the alias.
+func (file *File) astAliasGetters(serviceName string, results func(fallible bool) *ast.FieldList) (decls []ast.Decl) {
+	alias := file.Services[serviceName]
+	target := file.Services[alias.Alias]
+
+	ref := alias.Alias
+	if len(target.Arguments) > 0 {
+		ref += "(" + strings.Join(target.Arguments.Names(), ", ") + ")"
+	}
+
+	fallible := file.Services.IsFallible(serviceName)
+	decls = file.astGetter(target, "Get"+serviceName, results(fallible),
+		file.Services.UsesContext(serviceName),
+		newBlock(newReturn(newIdent(file.Services.getterCall(ref, true)))))
+
+	if fallible {
+		decls = append(decls, file.astMustGetter(target, serviceName, results(false),
+			file.Services.UsesContext(serviceName))...)
+	}
+
+	for _, decl := range decls {
+		decl.(*ast.FuncDecl).Doc = alias.astDeprecated()
+	}
+
+	return
+}
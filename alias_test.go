@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServices_Dependencies_Alias(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A"},
+		"B": {Alias: "A"},
+		"C": {Returns: "NewC(@{B})"},
+		"P": {Type: "*P", Scope: ScopePrototype},
+		"Q": {Alias: "P"},
+		"D": {Returns: "NewD(@{Q})"},
+		"R": {Type: "*R", Scope: ScopeRequest},
+		"S": {Alias: "R"},
+		"E": {Returns: "NewE(@{S})"},
+	}
+
+	assert.Equal(t, []string{"A"}, services.Dependencies("B"))
+	assert.Equal(t, []string{"B"}, services.Dependencies("C"))
+	assert.Nil(t, services.Dependencies("D"))
+	assert.Equal(t, []string{"R"}, services.CapturedRequestServices("E"))
+}
+
+func TestService_aliasDiagnostics(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A"},
+		"B": {Alias: "A"},
+		"C": {Alias: "B"},
+		"D": {Alias: "Aa"},
+		"E": {Decorates: "A"},
+		"F": {Alias: "E"},
+	}
+
+	for serviceName, expected := range map[string]string{
+		"A": "",
+		"B": "",
+		"C": "cannot alias an alias: B",
+		"D": "service does not exist: Aa (did you mean A?)",
+		"F": "cannot alias a decorator: E",
+	} {
+		t.Run(serviceName, func(t *testing.T) {
+			diagnostics := services[serviceName].aliasDiagnostics(serviceName, services)
+			if expected == "" {
+				assert.Nil(t, diagnostics)
+			} else if assert.Len(t, diagnostics, 1) {
+				assert.EqualError(t, diagnostics[0].Err, expected)
+			}
+		})
+	}
+}
+
+func TestFile_astAliasGetters_Deprecated(t *testing.T) {
+	source := generateSource(t, `services:
+  A:
+    type: '*A'
+    returns: NewA()
+    returns_error: true
+  B:
+    alias: A
+    deprecated: Use GetA instead.
+`)
+
+	assert.Contains(t, source, "\n// Deprecated: Use GetA instead.\n"+
+		"func (container *Container) GetB() (*A, error) {\n")
+	assert.Contains(t, source, "\n// Deprecated: Use GetA instead.\n"+
+		"func (container *Container) MustGetB() *A {\n")
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// durationPattern matches the durations parsed by time.ParseDuration.
+const durationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+)$`
+
+// jsonSchemaType returns the JSON Schema for a value of a Go type in a config
+// file. Types that cannot be described, such as structs, accept any value.
+func jsonSchemaType(ty string) map[string]interface{} {
+	ty = strings.TrimLeft(ty, "*")
+
+	switch {
+	case strings.HasPrefix(ty, "[]"):
+		return map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchemaType(ty[2:]),
+		}
+
+	case strings.HasPrefix(ty, "map[string]"):
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": jsonSchemaType(ty[len("map[string]"):]),
+		}
+	}
+
+	switch ty {
+	case "string":
+		return map[string]interface{}{"type": "string"}
+
+	case "bool":
+		return map[string]interface{}{"type": "boolean"}
+
+	case "int", "int8", "int16", "int32", "int64":
+		return map[string]interface{}{"type": "integer"}
+
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+
+	case "float32", "float64":
+		return map[string]interface{}{"type": "number"}
+
+	case "time.Duration":
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+
+	case "time.Time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	return map[string]interface{}{}
+}
+
+// JSONSchema returns the JSON Schema of a config file that can be loaded with
+// the generated LoadConfig. Every parameter is optional, any parameter that is
+// not in the config keeps its value.
+func (parameters Parameters) JSONSchema() ([]byte, error) {
+	properties := map[string]interface{}{}
+	for _, name := range parameters.Names() {
+		schema := jsonSchemaType(parameters[name].Type.LocalEntityType())
+		schema["description"] = string(parameters[name].Type)
+		properties[name] = schema
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}, "", "  ")
+}
+
+// runConfigSchema is the "config-schema" command. It writes the JSON Schema for
+// the config file of a container to w.
+func runConfigSchema(args []string, w io.Writer) error {
+	opts := Options{}
+	flags := flag.NewFlagSet("config-schema", flag.ContinueOnError)
+	flags.StringVar(&opts.Dir, "dir", ".",
+		"Directory that -config is relative to.")
+	flags.StringVar(&opts.Config, "config", "dingo.yml",
+		"Path to the YAML file that describes the parameters.")
+	flags.StringVar(&opts.Profile, "profile", "",
+		"Profile to use. The overlay for the profile is merged onto the\n"+
+			"config file.")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(),
+			"Usage: dingo config-schema [flags] [path]\n\n"+
+				"Writes the JSON Schema of the config file that is loaded by\n"+
+				"NewContainerFromConfig and LoadConfig. The path is a directory\n"+
+				"containing the config file, or the path to a config file.\n\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		opts = opts.ForPath(flags.Arg(0))
+	default:
+		flags.Usage()
+		return fmt.Errorf("config-schema accepts one path")
+	}
+
+	return configSchema(opts, w)
+}
+
+// configSchema writes the JSON Schema for the parameters of the config file.
+func configSchema(opts Options, w io.Writer) error {
+	file, err := parseOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Parameters.Diagnostics().Err(); err != nil {
+		return err
+	}
+
+	schema, err := file.Parameters.JSONSchema()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", schema)
+
+	return err
+}
+
+// astConfigFuncs creates NewContainer, NewContainerFromConfig and
+// Container.LoadConfig when there are parameters. A config is a JSON object, or
+// a YAML mapping if yamlConfig is set, of parameter names to their values. It is
+// decoded into the type of each parameter.
+func (file *File) astConfigFuncs() []ast.Decl {
+	if len(file.Parameters) == 0 {
+		return nil
+	}
+
+	file.addImport("fmt")
+	file.addImport("io")
+	file.addImport("os")
+
+	newContainer := newFunc("NewContainer", nil, []string{"*Container"}, newBlock(
+		&ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent("container")},
+			Rhs: []ast.Expr{newIdent("newContainer()")},
+		},
+		&ast.ExprStmt{X: newIdent("container.initParameters()")},
+		newReturn(newIdent("container")),
+	))
+
+	newContainerFromConfig := newFunc("NewContainerFromConfig",
+		[]string{"path string"}, []string{"*Container", "error"}, newBlock(
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("f"), newIdent("err")},
+				Rhs: []ast.Expr{newIdent("os.Open(path)")},
+			},
+			&ast.IfStmt{
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent("nil"), newIdent("err"))),
+			},
+			&ast.DeferStmt{Call: &ast.CallExpr{Fun: newIdent("f.Close")}},
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("container")},
+				Rhs: []ast.Expr{newIdent("NewContainer()")},
+			},
+			&ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("err")},
+					Rhs: []ast.Expr{newIdent("container.LoadConfig(f)")},
+				},
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent("nil"),
+					newIdent(`fmt.Errorf("%s: %w", path, err)`))),
+			},
+			newReturn(newIdent("container"), newIdent("nil")),
+		))
+
+	decls := []ast.Decl{newContainer, newContainerFromConfig, file.astLoadConfigFunc()}
+	if file.usesConfigDuration() {
+		decls = append(decls, astConfigDuration()...)
+	}
+
+	return decls
+}
+
+// usesConfigDuration returns true if a time.Duration parameter is decoded from
+// JSON. encoding/json decodes a time.Duration from a number of nanoseconds, but
+// it is written as a string such as "30s" in a config.
+func (file *File) usesConfigDuration() bool {
+	if file.yamlConfig {
+		return false
+	}
+
+	for _, parameter := range file.Parameters {
+		if parameter.Type.LocalEntityType() == "time.Duration" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// astLoadConfigFunc creates Container.LoadConfig. The values are decoded before
+// any parameter is changed, so the parameters are not changed if there is an
+// error. Only the parameters in the config are changed, and the parameters
+// whose value uses them are set again.
+func (file *File) astLoadConfigFunc() *ast.FuncDecl {
+	configType, decoder := "map[string]json.RawMessage", "json.NewDecoder(r)"
+	if file.yamlConfig {
+		configType, decoder = "map[string]yaml.Node", "yaml.NewDecoder(r)"
+		file.addImport("gopkg.in/yaml.v3")
+	} else {
+		file.addImport("encoding/json")
+	}
+
+	var clauses []ast.Stmt
+	for _, name := range file.Parameters.Names() {
+		field := "&parameters." + parameterFieldName(name)
+		decode := fmt.Sprintf("json.Unmarshal(value, %s)", field)
+		switch {
+		case file.yamlConfig:
+			decode = fmt.Sprintf("value.Decode(%s)", field)
+
+		case file.Parameters[name].Type.LocalEntityType() == "time.Duration":
+			decode = fmt.Sprintf("json.Unmarshal(value, (*configDuration)(%s))", field)
+		}
+
+		clauses = append(clauses, &ast.CaseClause{
+			List: []ast.Expr{newIdent(strconv.Quote(name))},
+			Body: []ast.Stmt{&ast.AssignStmt{
+				Tok: token.ASSIGN,
+				Lhs: []ast.Expr{newIdent("err")},
+				Rhs: []ast.Expr{newIdent(decode)},
+			}},
+		})
+	}
+
+	clauses = append(clauses, &ast.CaseClause{
+		Body: []ast.Stmt{newReturn(newIdent(`fmt.Errorf("unknown parameter: %s", name)`))},
+	})
+
+	stmts := []ast.Stmt{
+		&ast.DeclStmt{Decl: &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names: []*ast.Ident{newIdent("config")},
+				Type:  newIdent(configType),
+			}},
+		}},
+		&ast.IfStmt{
+			Init: &ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("err")},
+				Rhs: []ast.Expr{newIdent(decoder + ".Decode(&config)")},
+			},
+			Cond: newIdent("err != nil && err != io.EOF"),
+			Body: newBlock(newReturn(newIdent("err"))),
+		},
+		&ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent("parameters")},
+			Rhs: []ast.Expr{newIdent("container.Parameters")},
+		},
+		&ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent("changed")},
+			Rhs: []ast.Expr{newIdent("map[string]bool{}")},
+		},
+		&ast.RangeStmt{
+			Key:   newIdent("name"),
+			Value: newIdent("value"),
+			Tok:   token.DEFINE,
+			X:     newIdent("config"),
+			Body: newBlock(
+				&ast.DeclStmt{Decl: &ast.GenDecl{
+					Tok: token.VAR,
+					Specs: []ast.Spec{&ast.ValueSpec{
+						Names: []*ast.Ident{newIdent("err")},
+						Type:  newIdent("error"),
+					}},
+				}},
+				&ast.SwitchStmt{
+					Tag:  newIdent("name"),
+					Body: newBlock(clauses...),
+				},
+				&ast.IfStmt{
+					Cond: newIdent("err != nil"),
+					Body: newBlock(newReturn(newIdent(
+						`fmt.Errorf("parameter %s: %w", name, err)`))),
+				},
+				&ast.AssignStmt{
+					Tok: token.ASSIGN,
+					Lhs: []ast.Expr{newIdent("changed[name]")},
+					Rhs: []ast.Expr{newIdent("true")},
+				},
+			),
+		},
+		&ast.AssignStmt{
+			Tok: token.ASSIGN,
+			Lhs: []ast.Expr{newIdent("container.Parameters")},
+			Rhs: []ast.Expr{newIdent("parameters")},
+		},
+		&ast.RangeStmt{
+			Key: newIdent("name"),
+			Tok: token.DEFINE,
+			X:   newIdent("changed"),
+			Body: newBlock(&ast.AssignStmt{
+				Tok: token.ASSIGN,
+				Lhs: []ast.Expr{newIdent("container.parameterErrors[name]")},
+				Rhs: []ast.Expr{newIdent("nil")},
+			}),
+		},
+	}
+
+	// A parameter that is not in the config is set again if it uses one that
+	// is. The parameters are in order, so this includes the parameters that
+	// use them in turn.
+	for _, name := range file.Parameters.Order() {
+		var refs []string
+		for _, ref := range file.Parameters[name].Value.ParameterReferences() {
+			refs = append(refs, fmt.Sprintf("changed[%q]", ref))
+		}
+
+		if len(refs) == 0 {
+			continue
+		}
+
+		cond := strings.Join(refs, " || ")
+		if len(refs) > 1 {
+			cond = "(" + cond + ")"
+		}
+
+		stmts = append(stmts, &ast.IfStmt{
+			Cond: newIdent(fmt.Sprintf("!changed[%q] && %s", name, cond)),
+			Body: newBlock(
+				&ast.AssignStmt{
+					Tok: token.ASSIGN,
+					Lhs: []ast.Expr{newIdent(fmt.Sprintf("changed[%q]", name))},
+					Rhs: []ast.Expr{newIdent("true")},
+				},
+				&ast.AssignStmt{
+					Tok: token.ASSIGN,
+					Lhs: []ast.Expr{newIdent(fmt.Sprintf("container.parameterErrors[%q]", name))},
+					Rhs: []ast.Expr{newIdent(fmt.Sprintf("container.initParameter(%q)", name))},
+				},
+			),
+		})
+	}
+
+	return &ast.FuncDecl{
+		Name: newIdent("LoadConfig"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList("r io.Reader"),
+			Results: newFieldList("error"),
+		},
+		Body: newBlock(append(stmts, newReturn(newIdent("nil")))...),
+	}
+}
+
+// astConfigDuration creates the configDuration type that decodes a
+// time.Duration from a string in a JSON config.
+func astConfigDuration() []ast.Decl {
+	return []ast.Decl{
+		&ast.GenDecl{
+			Tok: token.TYPE,
+			Specs: []ast.Spec{&ast.TypeSpec{
+				Name: newIdent("configDuration"),
+				Type: newIdent("time.Duration"),
+			}},
+		},
+		&ast.FuncDecl{
+			Name: newIdent("UnmarshalJSON"),
+			Recv: newFieldList("d *configDuration"),
+			Type: &ast.FuncType{
+				Params:  newFieldList("data []byte"),
+				Results: newFieldList("error"),
+			},
+			Body: newBlock(
+				&ast.DeclStmt{Decl: &ast.GenDecl{
+					Tok: token.VAR,
+					Specs: []ast.Spec{&ast.ValueSpec{
+						Names: []*ast.Ident{newIdent("s")},
+						Type:  newIdent("string"),
+					}},
+				}},
+				&ast.IfStmt{
+					Init: &ast.AssignStmt{
+						Tok: token.DEFINE,
+						Lhs: []ast.Expr{newIdent("err")},
+						Rhs: []ast.Expr{newIdent("json.Unmarshal(data, &s)")},
+					},
+					Cond: newIdent("err != nil"),
+					Body: newBlock(newReturn(newIdent("err"))),
+				},
+				&ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("duration"), newIdent("err")},
+					Rhs: []ast.Expr{newIdent("time.ParseDuration(s)")},
+				},
+				&ast.IfStmt{
+					Cond: newIdent("err != nil"),
+					Body: newBlock(newReturn(newIdent("err"))),
+				},
+				&ast.AssignStmt{
+					Tok: token.ASSIGN,
+					Lhs: []ast.Expr{newIdent("*d")},
+					Rhs: []ast.Expr{newIdent("configDuration(duration)")},
+				},
+				newReturn(newIdent("nil")),
+			),
+		},
+	}
+}
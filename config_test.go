@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchemaType(t *testing.T) {
+	for ty, expected := range map[string]map[string]interface{}{
+		"string":  {"type": "string"},
+		"*string": {"type": "string"},
+		"bool":    {"type": "boolean"},
+		"int64":   {"type": "integer"},
+		"uint":    {"type": "integer", "minimum": 0},
+		"float64": {"type": "number"},
+		"[]int": {
+			"type":  "array",
+			"items": map[string]interface{}{"type": "integer"},
+		},
+		"map[string]bool": {
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "boolean"},
+		},
+		"time.Time": {"type": "string", "format": "date-time"},
+		"Settings":  {},
+	} {
+		t.Run(ty, func(t *testing.T) {
+			assert.Equal(t, expected, jsonSchemaType(ty))
+		})
+	}
+}
+
+func TestRunConfigSchema(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `parameters:
+  base_url:
+    type: string
+    value: '"http://localhost"'
+  retries:
+    type: int
+    value: 3
+`,
+		"dingo.prod.yml": `parameters:
+  timeout:
+    type: time.Duration
+    value: 5 * time.Second
+`,
+	})
+
+	var out bytes.Buffer
+	require.NoError(t, runConfigSchema([]string{"-profile", "prod", dir}, &out))
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "additionalProperties": false,
+  "properties": {
+    "base_url": {
+      "description": "string",
+      "type": "string"
+    },
+    "retries": {
+      "description": "int",
+      "type": "integer"
+    },
+    "timeout": {
+      "description": "time.Duration",
+      "pattern": "^[-+]?(0|(([0-9]+(\\.[0-9]*)?|\\.[0-9]+)(ns|us|µs|ms|s|m|h))+)$",
+      "type": "string"
+    }
+  },
+  "type": "object"
+}
+`, out.String())
+}
+
+func TestRunConfigSchema_Invalid(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `parameters:
+  retries:
+    value: 3
+`,
+	})
+
+	err := runConfigSchema([]string{filepath.Join(dir, "dingo.yml")}, &bytes.Buffer{})
+	assert.EqualError(t, err, filepath.Join(dir, "dingo.yml")+
+		":2:3: parameter retries must have a type")
+}
+
+func TestGenerateContainer_Config(t *testing.T) {
+	yml := `parameters:
+  host:
+    type: string
+    value: '"localhost"'
+  url:
+    type: string
+    value: '"http://" + %{host}'
+  health_url:
+    type: string
+    value: '%{url} + %{path}'
+  path:
+    type: string
+    value: '"/health"'
+  timeout:
+    type: time.Duration
+    value: time.Second
+`
+
+	source := generateSource(t, yml)
+	assert.NotContains(t, source, "gopkg.in/yaml.v3")
+	assert.Contains(t, source, "var config map[string]json.RawMessage\n")
+	assert.Contains(t, source, "err = json.Unmarshal(value, &parameters.Host)\n")
+	assert.Contains(t, source,
+		"err = json.Unmarshal(value, (*configDuration)(&parameters.Timeout))\n")
+	assert.Contains(t, source, "type configDuration time.Duration\n")
+
+	// The parameters that use the config are set again in order.
+	assert.Contains(t, source, `if !changed["url"] && changed["host"] {`+"\n"+
+		`		changed["url"] = true`+"\n"+
+		`		container.parameterErrors["url"] = container.initParameter("url")`)
+	assert.Contains(t, source, `if !changed["health_url"] && (changed["path"] || changed["url"]) {`)
+	assert.Less(t, strings.Index(source, `!changed["url"]`),
+		strings.Index(source, `!changed["health_url"]`))
+
+	file := parseYAML(t, yml)
+	file.yamlConfig = true
+	file, err := GenerateContainer(file, "main", "dingo.go")
+	require.NoError(t, err)
+	yamlSource, err := file.Source()
+	require.NoError(t, err)
+	assert.Contains(t, string(yamlSource), `"gopkg.in/yaml.v3"`)
+	assert.Contains(t, string(yamlSource), "var config map[string]yaml.Node\n")
+	assert.Contains(t, string(yamlSource), "err = value.Decode(&parameters.Timeout)\n")
+	assert.NotContains(t, string(yamlSource), "configDuration")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextReference is the placeholder, "@{ctx}", for the context.Context that
+// was passed to the getter. It is not a service.
+const ContextReference = "ctx"
+
+// UsesContext returns true if the expression contains "@{ctx}".
+func (e Expression) UsesContext() bool {
+	return strings.Contains(string(e), "@{"+ContextReference+"}")
+}
+
+// usesContext returns true if any expression used to create the service
+// contains "@{ctx}". The context is passed as the first argument to the
+// function of a prototype that uses it.
+func (service *Service) usesContext() bool {
+	for _, expr := range append(service.Expressions(), service.OnInit...) {
+		if expr.UsesContext() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UsesContext returns true if the service has a GetXContext getter. This is the
+// case when the service itself uses "@{ctx}", or when any service it depends on
+// does.
+func (services Services) UsesContext(serviceName string) bool {
+	return services.usesContext(services.Decorated(serviceName), map[string]bool{})
+}
+
+func (services Services) usesContext(serviceName string, seen map[string]bool) bool {
+	service := services[serviceName]
+	if service == nil || seen[serviceName] {
+		return false
+	}
+
+	if service.usesContext() {
+		return true
+	}
+
+	seen[serviceName] = true
+	for _, dep := range services.Dependencies(serviceName) {
+		if services.usesContext(dep, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getterCall returns the call to the getter for a reference, such as "A" or
+// "A(foo)". If withContext is true the context is passed to services that use
+// it so that cancellation applies to the whole construction.
+func (services Services) getterCall(ref string, withContext bool) string {
+	name, args := ref, ""
+	if i := strings.Index(ref, "("); i >= 0 {
+		name, args = ref[:i], strings.TrimSuffix(ref[i+1:], ")")
+	}
+
+	if withContext && services.UsesContext(name) {
+		if args == "" {
+			return fmt.Sprintf("container.Get%sContext(ctx)", name)
+		}
+
+		return fmt.Sprintf("container.Get%sContext(ctx, %s)", name, args)
+	}
+
+	return fmt.Sprintf("container.Get%s(%s)", name, args)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InnerReference is the placeholder, "@{inner}", for the service that a
+// decorator wraps. It is not a service.
+const InnerReference = "inner"
+
+// UsesInner returns true if the expression contains "@{inner}".
+func (e Expression) UsesInner() bool {
+	return strings.Contains(string(e), "@{"+InnerReference+"}")
+}
+
+// usesInner returns true if any expression used to create the service contains
+// "@{inner}".
+func (service *Service) usesInner() bool {
+	for _, expr := range append(service.Expressions(), service.OnInit...) {
+		if expr.UsesInner() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Decorators returns the services that decorate serviceName, from the
+// innermost to the outermost. Decorators with a higher priority are applied
+// first, otherwise they are sorted by name.
+func (services Services) Decorators(serviceName string) (decorators []string) {
+	for _, name := range services.ServiceNames() {
+		if service := services[name]; service != nil && service.Decorates == serviceName {
+			decorators = append(decorators, name)
+		}
+	}
+
+	sort.SliceStable(decorators, func(i, j int) bool {
+		return services[decorators[i]].Priority > services[decorators[j]].Priority
+	})
+
+	return
+}
+
+// Decorated returns the outermost decorator of serviceName, which is what is
+// injected when serviceName is referenced. If the service is not decorated
+// serviceName is returned.
+func (services Services) Decorated(serviceName string) string {
+	if decorators := services.Decorators(serviceName); len(decorators) > 0 {
+		return decorators[len(decorators)-1]
+	}
+
+	return serviceName
+}
+
+// innerName returns the service that is injected as "@{inner}" into the
+// decorator. This is the decorator before it, or the decorated service itself
+// for the innermost decorator.
+func (services Services) innerName(decoratorName string) string {
+	decorated := services[decoratorName].Decorates
+	decorators := services.Decorators(decorated)
+	for i, name := range decorators {
+		if name == decoratorName && i > 0 {
+			return decorators[i-1]
+		}
+	}
+
+	return decorated
+}
+
+// innerCall returns the call that creates the service injected as "@{inner}".
+func (services Services) innerCall(decoratorName string, withContext bool) string {
+	innerName := services.innerName(decoratorName)
+	if innerName != services[decoratorName].Decorates {
+		return services.getterCall(innerName, withContext)
+	}
+
+	if withContext && services.usesContext(innerName, map[string]bool{}) {
+		return fmt.Sprintf("container.inner%sContext(ctx)", innerName)
+	}
+
+	return fmt.Sprintf("container.inner%s()", innerName)
+}
+
+// decoratesDiagnostics returns a problem if the decorated service does not
+// exist or cannot be decorated.
+func (service *Service) decoratesDiagnostics(serviceName string, services Services) Diagnostics {
+	if service.Decorates == "" {
+		return nil
+	}
+
+	var err error
+	decorated := services[service.Decorates]
+	switch {
+	case decorated == nil:
+		err = unknownNameError("service", service.Decorates, services.ServiceNames())
+
+	case decorated.Decorates != "":
+		err = fmt.Errorf("cannot decorate a decorator: %s", service.Decorates)
+
+	case len(decorated.When) > 0:
+		err = fmt.Errorf("cannot decorate a service with when: %s", service.Decorates)
+
+	case decorated.Scope == ScopePrototype || len(decorated.Arguments) > 0:
+		err = fmt.Errorf("cannot decorate a prototype or a service with arguments: %s",
+			service.Decorates)
+
+	case decorated.Scope != service.Scope &&
+		!(isContainerScope(decorated.Scope) && isContainerScope(service.Scope)):
+		err = fmt.Errorf("decorator must have the same scope as %s", service.Decorates)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	return Diagnostics{{
+		Pos:     service.Position("decorates"),
+		Service: serviceName,
+		Err:     err,
+	}}
+}
+
+func isContainerScope(scope string) bool {
+	return scope == ScopeNotSet || scope == ScopeContainer
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var decoratorServices = Services{
+	"A": {Type: "*A", Interface: "I"},
+	"B": {Type: "*B", Decorates: "A", Returns: "NewB(@{inner})"},
+	"C": {Type: "*C", Decorates: "A", Returns: "NewC(@{inner})", Priority: 10},
+	"D": {Type: "*D", Decorates: "A", Properties: map[string]Expression{"I": "@{inner}"}},
+	"E": {Type: "*E", Returns: "NewE(@{A})"},
+	"F": {Type: "*F"},
+}
+
+func TestServices_Decorators(t *testing.T) {
+	assert.Equal(t, []string{"C", "B", "D"}, decoratorServices.Decorators("A"))
+	assert.Nil(t, decoratorServices.Decorators("E"))
+}
+
+func TestServices_Decorated(t *testing.T) {
+	assert.Equal(t, "D", decoratorServices.Decorated("A"))
+	assert.Equal(t, "F", decoratorServices.Decorated("F"))
+}
+
+func TestServices_innerName(t *testing.T) {
+	assert.Equal(t, "A", decoratorServices.innerName("C"))
+	assert.Equal(t, "C", decoratorServices.innerName("B"))
+	assert.Equal(t, "B", decoratorServices.innerName("D"))
+}
+
+func TestServices_Dependencies_Decorators(t *testing.T) {
+	assert.Equal(t, []string{"D"}, decoratorServices.Dependencies("E"))
+	assert.Equal(t, []string{"B"}, decoratorServices.Dependencies("D"))
+	assert.Equal(t, []string{"A"}, decoratorServices.Dependencies("C"))
+	assert.Nil(t, decoratorServices.Dependencies("A"))
+	assert.Nil(t, decoratorServices.Cycles())
+}
+
+func TestService_decoratesDiagnostics(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A"},
+		"B": {Decorates: "A"},
+		"C": {Decorates: "B"},
+		"D": {Decorates: "Aa"},
+		"P": {Scope: ScopePrototype},
+		"E": {Decorates: "P"},
+		"R": {Scope: ScopeRequest},
+		"F": {Decorates: "R"},
+		"G": {Decorates: "R", Scope: ScopeRequest},
+	}
+
+	for serviceName, expected := range map[string]string{
+		"A": "",
+		"B": "",
+		"C": "cannot decorate a decorator: B",
+		"D": "service does not exist: Aa (did you mean A?)",
+		"E": "cannot decorate a prototype or a service with arguments: P",
+		"F": "decorator must have the same scope as R",
+		"G": "",
+	} {
+		t.Run(serviceName, func(t *testing.T) {
+			diagnostics := services[serviceName].decoratesDiagnostics(serviceName, services)
+			if expected == "" {
+				assert.Nil(t, diagnostics)
+			} else if assert.Len(t, diagnostics, 1) {
+				assert.EqualError(t, diagnostics[0].Err, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Diagnostic is a problem with the YAML configuration. It includes the location
+// of the YAML that caused it so that it can be reported in the same format as
+// the Go compiler.
+type Diagnostic struct {
+	Pos     token.Position
+	Service string
+	Err     error
+}
+
+func (diagnostic *Diagnostic) Error() string {
+	if diagnostic.Service == "" {
+		return fmt.Sprintf("%s: %v", diagnostic.Pos, diagnostic.Err)
+	}
+
+	return fmt.Sprintf("%s: %s: %v", diagnostic.Pos, diagnostic.Service,
+		diagnostic.Err)
+}
+
+// Diagnostics is used to return all of the problems found at once, rather than
+// stopping at the first one.
+type Diagnostics []*Diagnostic
+
+func (diagnostics Diagnostics) Error() string {
+	var lines []string
+	for _, diagnostic := range diagnostics {
+		lines = append(lines, diagnostic.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Err returns nil if there are no diagnostics. Otherwise it returns the
+// diagnostics sorted by their location.
+func (diagnostics Diagnostics) Err() error {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Pos, diagnostics[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return diagnostics
+}
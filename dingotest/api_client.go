@@ -0,0 +1,16 @@
+package dingotest
+
+import "time"
+
+type APIClient struct {
+	URL     string
+	Timeout time.Duration
+}
+
+type APIRequest struct {
+	URL string
+}
+
+func NewAPIRequest(url, path string) *APIRequest {
+	return &APIRequest{URL: url + path}
+}
@@ -0,0 +1,41 @@
+package dingotest
+
+import "errors"
+
+// CloseLog records the order that services are closed.
+type CloseLog struct {
+	Names []string
+}
+
+type Connection struct {
+	Log *CloseLog
+}
+
+func (conn *Connection) Close() error {
+	conn.Log.Names = append(conn.Log.Names, "Connection")
+
+	return nil
+}
+
+type ConnectionPool struct {
+	Connection *Connection
+	Log        *CloseLog
+}
+
+func (pool *ConnectionPool) Shutdown() error {
+	pool.Log.Names = append(pool.Log.Names, "ConnectionPool")
+
+	return errors.New("pool is already shut down")
+}
+
+// Transaction is created for each request.
+type Transaction struct {
+	Connection *Connection
+	Log        *CloseLog
+}
+
+func (tx *Transaction) Rollback() error {
+	tx.Log.Names = append(tx.Log.Names, "Transaction")
+
+	return nil
+}
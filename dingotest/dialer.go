@@ -0,0 +1,29 @@
+package dingotest
+
+import "context"
+
+// Dialer needs a context to connect.
+type Dialer struct {
+	Context context.Context
+}
+
+func NewDialer(ctx context.Context) (*Dialer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Dialer{Context: ctx}, nil
+}
+
+type Client struct {
+	Dialer *Dialer
+}
+
+type Request struct {
+	Context context.Context
+	Dialer  *Dialer
+}
+
+func NewRequest(ctx context.Context, dialer *Dialer) *Request {
+	return &Request{Context: ctx, Dialer: dialer}
+}
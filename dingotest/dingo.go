@@ -2,39 +2,111 @@
 package dingotest
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	go_sub_pkg "github.com/elliotchance/dingo/dingotest/go-sub-pkg"
 	clockwork "github.com/jonboulle/clockwork"
+	"io"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	time "time"
 )
 
+type Parameters struct {
+	APITimeout time.Duration
+	APIURL     string
+	BaseURL    string
+}
 type Container struct {
-	AFunc            func(int, int) (bool, bool)
-	Clock            clockwork.Clock
-	CustomerWelcome  *CustomerWelcome
-	DependsOnTime    func(ParsedTime time.Time) time.Time
-	HTTPSignerClient *HTTPSignerClient
-	Now              func() time.Time
-	OtherPkg         *go_sub_pkg.Person
-	OtherPkg2        go_sub_pkg.Greeter
-	OtherPkg3        *go_sub_pkg.Person
-	ParsedTime       func(value string) time.Time
-	SendEmail        EmailSender
-	SendEmailError   *SendEmail
-	Signer           func(req *http.Request) *Signer
-	SomeEnv          *string
-	WhatsTheTime     *WhatsTheTime
-	WithEnv1         *SendEmail
-	WithEnv2         *SendEmail
+	Parameters                Parameters
+	AFunc                     func(int, int) (bool, bool)
+	APIClient                 *APIClient
+	APIRequest                func(paramAPIURL string, path string) *APIRequest
+	Cache                     *Cache
+	CacheHealth               HealthChecker
+	CachedUserRepo            *CachedUserRepo
+	Child                     *Child
+	Client                    *Client
+	Clock                     clockwork.Clock
+	CloseLog                  *CloseLog
+	Connection                *Connection
+	ConnectionPool            *ConnectionPool
+	CustomerWelcome           *CustomerWelcome
+	CustomerWelcomeFrom       *CustomerWelcome
+	CustomerWelcomeFromAlias  *CustomerWelcome
+	CustomerWelcomePrototype  func(SendEmail EmailSender, appid string) *CustomerWelcome
+	CustomerWelcomePrototype2 func(SendEmail EmailSender, canaryConfig *v1.ObjectMetaAccessor) *CustomerWelcome
+	Database                  *Database
+	DatabaseHealth            HealthChecker
+	DependsOnTime             func(ParsedTime time.Time) time.Time
+	Dialer                    *Dialer
+	HTTPSignerClient          *HTTPSignerClient
+	HealthCheck               *HealthCheck
+	HealthCheckPrototype      func(taggedHealth []HealthChecker) *HealthCheck
+	LogNotifier               *LogNotifier
+	LoggedUserRepo            *LoggedUserRepo
+	MissingSecret             *string
+	Now                       func() time.Time
+	OtherPkg                  *go_sub_pkg.Person
+	OtherPkg2                 go_sub_pkg.Greeter
+	OtherPkg3                 *go_sub_pkg.Person
+	Parent                    *Parent
+	ParsedTime                func(value string) time.Time
+	ParsedTimeOrError         func(value string) (time.Time, error)
+	QueueHealth               HealthChecker
+	Report                    func(lazySendEmailFrom func() (*SendEmail, error)) *Report
+	Request                   func(ctx context.Context, Dialer *Dialer) *Request
+	SMSNotifier               *SMSNotifier
+	Secrets                   *Secrets
+	SendEmail                 EmailSender
+	SendEmailError            *SendEmail
+	SendEmailFrom             *SendEmail
+	ServerAddress             func(envSERVER_HOST string, envSERVER_PORT int) string
+	ServerConfig              *ServerConfig
+	ServerWorkers             *int
+	Signer                    func(req *http.Request) *Signer
+	SomeEnv                   *string
+	StartupLog                *StartupLog
+	StartupMailer             *SendEmail
+	ThreadSafeSendEmail       *SendEmail
+	Traced                    *Traced
+	Transaction               *Transaction
+	UserRepo                  UserRepo
+	UserService               *UserService
+	WhatsTheTime              *WhatsTheTime
+	WithEnv1                  *SendEmail
+	WithEnv2                  *SendEmail
+	mutexThreadSafeSendEmail  sync.Mutex
+	closers                   []func() error
+	closersMutex              sync.Mutex
+	parent                    *Container
+	parameterErrors           map[string]error
 }
 
 var DefaultContainer = NewContainer()
 
-func NewContainer() *Container {
-	return &Container{DependsOnTime: func(ParsedTime time.Time) time.Time {
+func newContainer() *Container {
+	return &Container{APIRequest: func(paramAPIURL string, path string) *APIRequest {
+		service := NewAPIRequest(paramAPIURL, path)
+		return service
+	}, CustomerWelcomePrototype: func(SendEmail EmailSender, appid string) *CustomerWelcome {
+		service := NewCustomerWelcome(SendEmail)
+		return service
+	}, CustomerWelcomePrototype2: func(SendEmail EmailSender, canaryConfig *v1.ObjectMetaAccessor) *CustomerWelcome {
+		service := NewCustomerWelcome(SendEmail)
+		return service
+	}, DependsOnTime: func(ParsedTime time.Time) time.Time {
 		service := ParsedTime
 		return service
+	}, HealthCheckPrototype: func(taggedHealth []HealthChecker) *HealthCheck {
+		service := NewHealthCheck(taggedHealth)
+		return service
 	}, Now: func() time.Time {
 		service := time.Now()
 		return service
@@ -44,12 +116,166 @@ func NewContainer() *Container {
 			return time.Now()
 		}
 		return service
+	}, ParsedTimeOrError: func(value string) (time.Time, error) {
+		service, err := time.Parse(time.RFC822, value)
+		if err != nil {
+			return *new(time.Time), err
+		}
+		return service, nil
+	}, Report: func(lazySendEmailFrom func() (*SendEmail, error)) *Report {
+		service := NewReport(lazySendEmailFrom)
+		return service
+	}, Request: func(ctx context.Context, Dialer *Dialer) *Request {
+		service := NewRequest(ctx, Dialer)
+		return service
+	}, ServerAddress: func(envSERVER_HOST string, envSERVER_PORT int) string {
+		service := fmt.Sprintf("%s:%d", envSERVER_HOST, envSERVER_PORT)
+		return service
 	}, Signer: func(req *http.Request) *Signer {
 		service := NewSigner(req)
 		return service
 	}}
 }
+func NewContainer() *Container {
+	container := newContainer()
+	container.initParameters()
+	return container
+}
+func NewContainerFromConfig(path string) (*Container, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	container := NewContainer()
+	if err := container.LoadConfig(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return container, nil
+}
+func (container *Container) LoadConfig(r io.Reader) error {
+	var config map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&config); err != nil && err != io.EOF {
+		return err
+	}
+	parameters := container.Parameters
+	changed := map[string]bool{}
+	for name, value := range config {
+		var err error
+		switch name {
+		case "api_timeout":
+			err = json.Unmarshal(value, (*configDuration)(&parameters.APITimeout))
+		case "api_url":
+			err = json.Unmarshal(value, &parameters.APIURL)
+		case "base_url":
+			err = json.Unmarshal(value, &parameters.BaseURL)
+		default:
+			return fmt.Errorf("unknown parameter: %s", name)
+		}
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", name, err)
+		}
+		changed[name] = true
+	}
+	container.Parameters = parameters
+	for name := range changed {
+		container.parameterErrors[name] = nil
+	}
+	if !changed["api_url"] && changed["base_url"] {
+		changed["api_url"] = true
+		container.parameterErrors["api_url"] = container.initParameter("api_url")
+	}
+	return nil
+}
+
+type configDuration time.Duration
+
+func (d *configDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = configDuration(duration)
+	return nil
+}
+func (container *Container) NewScope() *Container {
+	scope := newContainer()
+	scope.parent = container
+	scope.Parameters, scope.parameterErrors = container.Parameters, container.parameterErrors
+	scope.APIRequest = container.APIRequest
+	scope.CustomerWelcomePrototype = container.CustomerWelcomePrototype
+	scope.CustomerWelcomePrototype2 = container.CustomerWelcomePrototype2
+	scope.DependsOnTime = container.DependsOnTime
+	scope.HealthCheckPrototype = container.HealthCheckPrototype
+	scope.Now = container.Now
+	scope.ParsedTime = container.ParsedTime
+	scope.ParsedTimeOrError = container.ParsedTimeOrError
+	scope.Report = container.Report
+	scope.Request = container.Request
+	scope.ServerAddress = container.ServerAddress
+	scope.Signer = container.Signer
+	return scope
+}
+func (container *Container) addCloser(closer func() error) {
+	container.closersMutex.Lock()
+	defer container.closersMutex.Unlock()
+	container.closers = append(container.closers, closer)
+}
+func (container *Container) Close() error {
+	container.closersMutex.Lock()
+	closers := container.closers
+	container.closers = nil
+	container.closersMutex.Unlock()
+	var errs []string
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i](); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+func (container *Container) Init() error {
+	return container.Start(context.Background())
+}
+func (container *Container) Start(ctx context.Context) (err error) {
+	var serviceName string
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", serviceName, r)
+		}
+	}()
+	serviceName = "Database"
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	container.GetDatabase()
+	serviceName = "Cache"
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	container.GetCache()
+	serviceName = "StartupMailer"
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := container.GetStartupMailer(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAFunc is defined in dingo.yml.
 func (container *Container) GetAFunc() func(int, int) (bool, bool) {
+	if container.parent != nil && container.AFunc == nil {
+		return container.parent.GetAFunc()
+	}
 	if container.AFunc == nil {
 		service := func(a, b int) (c, d bool) {
 			c = (a + b) != 0
@@ -58,111 +284,1138 @@ func (container *Container) GetAFunc() func(int, int) (bool, bool) {
 			return
 		}
 
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.AFunc = service
 	}
 	return container.AFunc
 }
+
+// GetAPIClient is defined in dingo.yml.
+func (container *Container) GetAPIClient() (*APIClient, error) {
+	if container.parent != nil && container.APIClient == nil {
+		return container.parent.GetAPIClient()
+	}
+	if container.APIClient == nil {
+		if err := container.parameterErrors["api_timeout"]; err != nil {
+			return nil, fmt.Errorf("APIClient: %w", err)
+		}
+		service := &APIClient{}
+		service.Timeout = container.Parameters.APITimeout
+		service.URL = container.Parameters.APIURL
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.APIClient = service
+	}
+	return container.APIClient, nil
+}
+
+// MustGetAPIClient is defined in dingo.yml.
+func (container *Container) MustGetAPIClient() *APIClient {
+	service, err := container.GetAPIClient()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetAPIRequest is defined in dingo.yml.
+func (container *Container) GetAPIRequest(path string) *APIRequest {
+	return container.APIRequest(container.Parameters.APIURL, path)
+}
+
+// GetCache is defined in dingo.yml.
+func (container *Container) GetCache() *Cache {
+	if container.parent != nil && container.Cache == nil {
+		return container.parent.GetCache()
+	}
+	if container.Cache == nil {
+		service := &Cache{}
+		service.Database = container.GetDatabase()
+		service.Log = container.GetStartupLog()
+		container.GetStartupLog().Add("Cache")
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Cache = service
+	}
+	return container.Cache
+}
+
+// GetCacheHealth is defined in dingo.yml.
+func (container *Container) GetCacheHealth() HealthChecker {
+	if container.parent != nil && container.CacheHealth == nil {
+		return container.parent.GetCacheHealth()
+	}
+	if container.CacheHealth == nil {
+		service := &NamedHealthCheck{}
+		service.Name = "cache"
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.CacheHealth = service
+	}
+	return container.CacheHealth
+}
+
+// GetCachedUserRepo is defined in dingo.yml.
+func (container *Container) GetCachedUserRepo() *CachedUserRepo {
+	if container.parent != nil && container.CachedUserRepo == nil {
+		return container.parent.GetCachedUserRepo()
+	}
+	if container.CachedUserRepo == nil {
+		service := NewCachedUserRepo(container.innerUserRepo())
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.CachedUserRepo = service
+	}
+	return container.CachedUserRepo
+}
+
+// GetChild is defined in dingo.yml.
+func (container *Container) GetChild() *Child {
+	if container.parent != nil && container.Child == nil {
+		return container.parent.GetChild()
+	}
+	if container.Child == nil {
+		service := &Child{}
+		service.Parent = func() *Parent { return container.GetParent() }
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Child = service
+	}
+	return container.Child
+}
+
+// GetClientContext is defined in dingo.yml.
+func (container *Container) GetClientContext(ctx context.Context) (*Client, error) {
+	if container.parent != nil && container.Client == nil {
+		return container.parent.GetClientContext(ctx)
+	}
+	if container.Client == nil {
+		depDialer, err := container.GetDialerContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Client: %w", err)
+		}
+		service := &Client{}
+		service.Dialer = depDialer
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Client = service
+	}
+	return container.Client, nil
+}
+
+// GetClient is defined in dingo.yml.
+func (container *Container) GetClient() (*Client, error) {
+	return container.GetClientContext(context.Background())
+}
+
+// MustGetClient is defined in dingo.yml.
+func (container *Container) MustGetClient() *Client {
+	service, err := container.GetClient()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// MustGetClientContext is defined in dingo.yml.
+func (container *Container) MustGetClientContext(ctx context.Context) *Client {
+	service, err := container.GetClientContext(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetClock is defined in dingo.yml.
 func (container *Container) GetClock() clockwork.Clock {
+	if container.parent != nil && container.Clock == nil {
+		return container.parent.GetClock()
+	}
 	if container.Clock == nil {
 		service := clockwork.NewRealClock()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.Clock = service
 	}
 	return container.Clock
 }
+
+// GetCloseLog is defined in dingo.yml.
+func (container *Container) GetCloseLog() *CloseLog {
+	if container.parent != nil && container.CloseLog == nil {
+		return container.parent.GetCloseLog()
+	}
+	if container.CloseLog == nil {
+		service := &CloseLog{}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.CloseLog = service
+	}
+	return container.CloseLog
+}
+
+// GetConnection is defined in dingo.yml.
+func (container *Container) GetConnection() *Connection {
+	if container.parent != nil && container.Connection == nil {
+		return container.parent.GetConnection()
+	}
+	if container.Connection == nil {
+		service := &Connection{}
+		service.Log = container.GetCloseLog()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Connection = service
+	}
+	return container.Connection
+}
+
+// GetConnectionPool is defined in dingo.yml.
+func (container *Container) GetConnectionPool() *ConnectionPool {
+	if container.parent != nil && container.ConnectionPool == nil {
+		return container.parent.GetConnectionPool()
+	}
+	if container.ConnectionPool == nil {
+		service := &ConnectionPool{}
+		service.Connection = container.GetConnection()
+		service.Log = container.GetCloseLog()
+		container.addCloser(func() error {
+			return service.Shutdown()
+		})
+		container.ConnectionPool = service
+	}
+	return container.ConnectionPool
+}
+
+// GetCustomerWelcome is defined in dingo.yml.
 func (container *Container) GetCustomerWelcome() *CustomerWelcome {
+	if container.parent != nil && container.CustomerWelcome == nil {
+		return container.parent.GetCustomerWelcome()
+	}
 	if container.CustomerWelcome == nil {
 		service := NewCustomerWelcome(container.GetSendEmail())
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.CustomerWelcome = service
 	}
 	return container.CustomerWelcome
 }
+
+// GetCustomerWelcomeFrom is defined in dingo.yml.
+func (container *Container) GetCustomerWelcomeFrom() (*CustomerWelcome, error) {
+	if container.parent != nil && container.CustomerWelcomeFrom == nil {
+		return container.parent.GetCustomerWelcomeFrom()
+	}
+	if container.CustomerWelcomeFrom == nil {
+		depSendEmailFrom, err := container.GetSendEmailFrom()
+		if err != nil {
+			return nil, fmt.Errorf("CustomerWelcomeFrom: %w", err)
+		}
+		service := NewCustomerWelcome(depSendEmailFrom)
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.CustomerWelcomeFrom = service
+	}
+	return container.CustomerWelcomeFrom, nil
+}
+
+// MustGetCustomerWelcomeFrom is defined in dingo.yml.
+func (container *Container) MustGetCustomerWelcomeFrom() *CustomerWelcome {
+	service, err := container.GetCustomerWelcomeFrom()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetCustomerWelcomeFromAlias is defined in dingo.yml.
+func (container *Container) GetCustomerWelcomeFromAlias() *CustomerWelcome {
+	if container.parent != nil && container.CustomerWelcomeFromAlias == nil {
+		return container.parent.GetCustomerWelcomeFromAlias()
+	}
+	if container.CustomerWelcomeFromAlias == nil {
+		service := NewCustomerWelcome(container.GetEmailSender())
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.CustomerWelcomeFromAlias = service
+	}
+	return container.CustomerWelcomeFromAlias
+}
+
+// GetCustomerWelcomePrototype is defined in dingo.yml.
+func (container *Container) GetCustomerWelcomePrototype(appid string) *CustomerWelcome {
+	return container.CustomerWelcomePrototype(container.GetSendEmail(), appid)
+}
+
+// GetCustomerWelcomePrototype2 is defined in dingo.yml.
+func (container *Container) GetCustomerWelcomePrototype2(canaryConfig *v1.ObjectMetaAccessor) *CustomerWelcome {
+	return container.CustomerWelcomePrototype2(container.GetSendEmail(), canaryConfig)
+}
+
+// GetDatabase is defined in dingo.yml.
+func (container *Container) GetDatabase() *Database {
+	if container.parent != nil && container.Database == nil {
+		return container.parent.GetDatabase()
+	}
+	if container.Database == nil {
+		service := &Database{}
+		service.Log = container.GetStartupLog()
+		service.Connect()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Database = service
+	}
+	return container.Database
+}
+
+// GetDatabaseHealth is defined in dingo.yml.
+func (container *Container) GetDatabaseHealth() HealthChecker {
+	if container.parent != nil && container.DatabaseHealth == nil {
+		return container.parent.GetDatabaseHealth()
+	}
+	if container.DatabaseHealth == nil {
+		service := &NamedHealthCheck{}
+		service.Name = "database"
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.DatabaseHealth = service
+	}
+	return container.DatabaseHealth
+}
+
+// GetDependsOnTime is defined in dingo.yml.
 func (container *Container) GetDependsOnTime() time.Time {
 	return container.DependsOnTime(container.GetParsedTime("13 Jan 06 15:04 MST"))
 }
+
+// GetDialerContext is defined in dingo.yml.
+func (container *Container) GetDialerContext(ctx context.Context) (*Dialer, error) {
+	if container.parent != nil && container.Dialer == nil {
+		return container.parent.GetDialerContext(ctx)
+	}
+	if container.Dialer == nil {
+		service, err := NewDialer(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Dialer: %w", err)
+		}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Dialer = service
+	}
+	return container.Dialer, nil
+}
+
+// GetDialer is defined in dingo.yml.
+func (container *Container) GetDialer() (*Dialer, error) {
+	return container.GetDialerContext(context.Background())
+}
+
+// MustGetDialer is defined in dingo.yml.
+func (container *Container) MustGetDialer() *Dialer {
+	service, err := container.GetDialer()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// MustGetDialerContext is defined in dingo.yml.
+func (container *Container) MustGetDialerContext(ctx context.Context) *Dialer {
+	service, err := container.GetDialerContext(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetEmailSender is defined in dingo.yml.
+//
+// Deprecated: Use GetSendEmail instead.
+func (container *Container) GetEmailSender() EmailSender {
+	return container.GetSendEmail()
+}
+
+// GetHTTPSignerClient is defined in dingo.yml.
 func (container *Container) GetHTTPSignerClient() *HTTPSignerClient {
+	if container.parent != nil && container.HTTPSignerClient == nil {
+		return container.parent.GetHTTPSignerClient()
+	}
 	if container.HTTPSignerClient == nil {
 		service := &HTTPSignerClient{}
 		service.CreateSigner = container.Signer
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.HTTPSignerClient = service
 	}
 	return container.HTTPSignerClient
 }
+
+// GetHealthCheck is defined in dingo.yml.
+func (container *Container) GetHealthCheck() *HealthCheck {
+	if container.parent != nil && container.HealthCheck == nil {
+		return container.parent.GetHealthCheck()
+	}
+	if container.HealthCheck == nil {
+		service := NewHealthCheck([]HealthChecker{container.GetCacheHealth(), container.GetDatabaseHealth(), container.GetQueueHealth()})
+		service.ByName = map[string]HealthChecker{"CacheHealth": container.GetCacheHealth(), "DatabaseHealth": container.GetDatabaseHealth(), "QueueHealth": container.GetQueueHealth()}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.HealthCheck = service
+	}
+	return container.HealthCheck
+}
+
+// GetHealthCheckPrototype is defined in dingo.yml.
+func (container *Container) GetHealthCheckPrototype() *HealthCheck {
+	return container.HealthCheckPrototype([]HealthChecker{container.GetCacheHealth(), container.GetDatabaseHealth(), container.GetQueueHealth()})
+}
+
+// GetLogNotifier is defined in services/notifier.yml.
+func (container *Container) GetLogNotifier() *LogNotifier {
+	if container.parent != nil && container.LogNotifier == nil {
+		return container.parent.GetLogNotifier()
+	}
+	if container.LogNotifier == nil {
+		service := &LogNotifier{}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.LogNotifier = service
+	}
+	return container.LogNotifier
+}
+
+// GetLoggedUserRepo is defined in dingo.yml.
+func (container *Container) GetLoggedUserRepo() *LoggedUserRepo {
+	if container.parent != nil && container.LoggedUserRepo == nil {
+		return container.parent.GetLoggedUserRepo()
+	}
+	if container.LoggedUserRepo == nil {
+		service := &LoggedUserRepo{}
+		service.Inner = container.GetCachedUserRepo()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.LoggedUserRepo = service
+	}
+	return container.LoggedUserRepo
+}
+
+// GetMissingSecret is defined in dingo.yml.
+func (container *Container) GetMissingSecret() (string, error) {
+	if container.parent != nil && container.MissingSecret == nil {
+		return container.parent.GetMissingSecret()
+	}
+	if container.MissingSecret == nil {
+		file_testdata_missing_cecbd3a2, err := container.readFile("testdata/missing", "", true)
+		if err != nil {
+			return *new(string), fmt.Errorf("MissingSecret: %w", err)
+		}
+		service := file_testdata_missing_cecbd3a2
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.MissingSecret = &service
+	}
+	return *container.MissingSecret, nil
+}
+
+// MustGetMissingSecret is defined in dingo.yml.
+func (container *Container) MustGetMissingSecret() string {
+	service, err := container.GetMissingSecret()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetNotifier is defined in services/notifier.yml.
+func (container *Container) GetNotifier() Notifier {
+	if os.Getenv("NOTIFIER") == "sms" {
+		return container.GetSMSNotifier()
+	}
+	return container.GetLogNotifier()
+}
+
+// GetNow is defined in dingo.yml.
 func (container *Container) GetNow() time.Time {
 	return container.Now()
 }
+
+// GetOtherPkg is defined in dingo.yml.
 func (container *Container) GetOtherPkg() *go_sub_pkg.Person {
+	if container.parent != nil && container.OtherPkg == nil {
+		return container.parent.GetOtherPkg()
+	}
 	if container.OtherPkg == nil {
 		service := &go_sub_pkg.Person{}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.OtherPkg = service
 	}
 	return container.OtherPkg
 }
+
+// GetOtherPkg2 is defined in dingo.yml.
 func (container *Container) GetOtherPkg2() go_sub_pkg.Greeter {
+	if container.parent != nil && container.OtherPkg2 == nil {
+		return container.parent.GetOtherPkg2()
+	}
 	if container.OtherPkg2 == nil {
 		service := go_sub_pkg.NewPerson()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.OtherPkg2 = service
 	}
 	return container.OtherPkg2
 }
+
+// GetOtherPkg3 is defined in dingo.yml.
 func (container *Container) GetOtherPkg3() go_sub_pkg.Person {
+	if container.parent != nil && container.OtherPkg3 == nil {
+		return container.parent.GetOtherPkg3()
+	}
 	if container.OtherPkg3 == nil {
 		service := go_sub_pkg.Person{}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.OtherPkg3 = &service
 	}
 	return *container.OtherPkg3
 }
+
+// GetParent is defined in dingo.yml.
+func (container *Container) GetParent() *Parent {
+	if container.parent != nil && container.Parent == nil {
+		return container.parent.GetParent()
+	}
+	if container.Parent == nil {
+		service := &Parent{}
+		service.Child = container.GetChild()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Parent = service
+	}
+	return container.Parent
+}
+
+// GetParseTime is defined in dingo.yml.
+func (container *Container) GetParseTime(value string) time.Time {
+	return container.GetParsedTime(value)
+}
+
+// GetParsedTime is defined in dingo.yml.
 func (container *Container) GetParsedTime(value string) time.Time {
 	return container.ParsedTime(value)
 }
+
+// GetParsedTimeOrError is defined in dingo.yml.
+func (container *Container) GetParsedTimeOrError(value string) (time.Time, error) {
+	service, err := container.ParsedTimeOrError(value)
+	if err != nil {
+		return *new(time.Time), fmt.Errorf("ParsedTimeOrError: %w", err)
+	}
+	return service, nil
+}
+
+// MustGetParsedTimeOrError is defined in dingo.yml.
+func (container *Container) MustGetParsedTimeOrError(value string) time.Time {
+	service, err := container.GetParsedTimeOrError(value)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetQueueHealth is defined in dingo.yml.
+func (container *Container) GetQueueHealth() HealthChecker {
+	if container.parent != nil && container.QueueHealth == nil {
+		return container.parent.GetQueueHealth()
+	}
+	if container.QueueHealth == nil {
+		service := &NamedHealthCheck{}
+		service.Name = "queue"
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.QueueHealth = service
+	}
+	return container.QueueHealth
+}
+
+// GetReport is defined in dingo.yml.
+func (container *Container) GetReport() *Report {
+	return container.Report(func() (*SendEmail, error) { return container.GetSendEmailFrom() })
+}
+
+// GetRequestContext is defined in dingo.yml.
+func (container *Container) GetRequestContext(ctx context.Context) (*Request, error) {
+	depDialer, err := container.GetDialerContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Request: %w", err)
+	}
+	return container.Request(ctx, depDialer), nil
+}
+
+// GetRequest is defined in dingo.yml.
+func (container *Container) GetRequest() (*Request, error) {
+	return container.GetRequestContext(context.Background())
+}
+
+// MustGetRequest is defined in dingo.yml.
+func (container *Container) MustGetRequest() *Request {
+	service, err := container.GetRequest()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// MustGetRequestContext is defined in dingo.yml.
+func (container *Container) MustGetRequestContext(ctx context.Context) *Request {
+	service, err := container.GetRequestContext(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetSMSNotifier is defined in services/notifier.yml.
+func (container *Container) GetSMSNotifier() *SMSNotifier {
+	if container.parent != nil && container.SMSNotifier == nil {
+		return container.parent.GetSMSNotifier()
+	}
+	if container.SMSNotifier == nil {
+		service := &SMSNotifier{}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.SMSNotifier = service
+	}
+	return container.SMSNotifier
+}
+
+// GetSecrets is defined in dingo.yml.
+func (container *Container) GetSecrets() (*Secrets, error) {
+	if container.parent != nil && container.Secrets == nil {
+		return container.parent.GetSecrets()
+	}
+	if container.Secrets == nil {
+		file_testdata_api_token_96b47f64, err := container.readFile("testdata/api_token", "none", false)
+		if err != nil {
+			return nil, fmt.Errorf("Secrets: %w", err)
+		}
+		file_testdata_db_password_8f476b3c, err := container.readFile("testdata/db_password", "", true)
+		if err != nil {
+			return nil, fmt.Errorf("Secrets: %w", err)
+		}
+		service := &Secrets{}
+		service.APIToken = file_testdata_api_token_96b47f64
+		service.DatabasePassword = file_testdata_db_password_8f476b3c
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Secrets = service
+	}
+	return container.Secrets, nil
+}
+
+// MustGetSecrets is defined in dingo.yml.
+func (container *Container) MustGetSecrets() *Secrets {
+	service, err := container.GetSecrets()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetSendEmail is defined in dingo.yml.
 func (container *Container) GetSendEmail() EmailSender {
+	if container.parent != nil && container.SendEmail == nil {
+		return container.parent.GetSendEmail()
+	}
 	if container.SendEmail == nil {
 		service := &SendEmail{}
 		service.From = "hi@welcome.com"
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.SendEmail = service
 	}
 	return container.SendEmail
 }
+
+// GetSendEmailError is defined in dingo.yml.
 func (container *Container) GetSendEmailError() *SendEmail {
+	if container.parent != nil && container.SendEmailError == nil {
+		return container.parent.GetSendEmailError()
+	}
 	if container.SendEmailError == nil {
 		service, err := NewSendEmail()
 		if err != nil {
 			panic(err)
 		}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.SendEmailError = service
 	}
 	return container.SendEmailError
 }
+
+// GetSendEmailFrom is defined in dingo.yml.
+func (container *Container) GetSendEmailFrom() (*SendEmail, error) {
+	if container.parent != nil && container.SendEmailFrom == nil {
+		return container.parent.GetSendEmailFrom()
+	}
+	if container.SendEmailFrom == nil {
+		service, err := NewSendEmailFrom(os.Getenv("SEND_EMAIL_FROM"))
+		if err != nil {
+			return nil, fmt.Errorf("SendEmailFrom: %w", err)
+		}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.SendEmailFrom = service
+	}
+	return container.SendEmailFrom, nil
+}
+
+// MustGetSendEmailFrom is defined in dingo.yml.
+func (container *Container) MustGetSendEmailFrom() *SendEmail {
+	service, err := container.GetSendEmailFrom()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetSendEmailFromAlias is defined in dingo.yml.
+func (container *Container) GetSendEmailFromAlias() (*SendEmail, error) {
+	return container.GetSendEmailFrom()
+}
+
+// MustGetSendEmailFromAlias is defined in dingo.yml.
+func (container *Container) MustGetSendEmailFromAlias() *SendEmail {
+	service, err := container.GetSendEmailFromAlias()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetServerAddress is defined in dingo.yml.
+func (container *Container) GetServerAddress() (string, error) {
+	envSERVER_HOST, _ := container.envString("SERVER_HOST", "localhost", false)
+	envSERVER_PORT, err := container.envInt("SERVER_PORT", "8080", false)
+	if err != nil {
+		return *new(string), fmt.Errorf("ServerAddress: %w", err)
+	}
+	return container.ServerAddress(envSERVER_HOST, envSERVER_PORT), nil
+}
+
+// MustGetServerAddress is defined in dingo.yml.
+func (container *Container) MustGetServerAddress() string {
+	service, err := container.GetServerAddress()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetServerConfig is defined in dingo.yml.
+func (container *Container) GetServerConfig() (*ServerConfig, error) {
+	if container.parent != nil && container.ServerConfig == nil {
+		return container.parent.GetServerConfig()
+	}
+	if container.ServerConfig == nil {
+		envSERVER_API_KEY, err := container.envString("SERVER_API_KEY", "", true)
+		if err != nil {
+			return nil, fmt.Errorf("ServerConfig: %w", err)
+		}
+		envSERVER_DEBUG, err := container.envBool("SERVER_DEBUG", "", false)
+		if err != nil {
+			return nil, fmt.Errorf("ServerConfig: %w", err)
+		}
+		envSERVER_HOST, _ := container.envString("SERVER_HOST", "localhost", false)
+		envSERVER_PORT, err := container.envInt("SERVER_PORT", "8080", false)
+		if err != nil {
+			return nil, fmt.Errorf("ServerConfig: %w", err)
+		}
+		envSERVER_TIMEOUT, err := container.envDuration("SERVER_TIMEOUT", "5s", false)
+		if err != nil {
+			return nil, fmt.Errorf("ServerConfig: %w", err)
+		}
+		service := &ServerConfig{}
+		service.APIKey = envSERVER_API_KEY
+		service.Debug = envSERVER_DEBUG
+		service.Host = envSERVER_HOST
+		service.Port = envSERVER_PORT
+		service.Timeout = envSERVER_TIMEOUT
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.ServerConfig = service
+	}
+	return container.ServerConfig, nil
+}
+
+// MustGetServerConfig is defined in dingo.yml.
+func (container *Container) MustGetServerConfig() *ServerConfig {
+	service, err := container.GetServerConfig()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetServerWorkers is defined in dingo.yml.
+func (container *Container) GetServerWorkers() (int, error) {
+	if container.parent != nil && container.ServerWorkers == nil {
+		return container.parent.GetServerWorkers()
+	}
+	if container.ServerWorkers == nil {
+		envSERVER_WORKERS, err := container.envInt("SERVER_WORKERS", "", true)
+		if err != nil {
+			return *new(int), fmt.Errorf("ServerWorkers: %w", err)
+		}
+		service := envSERVER_WORKERS
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.ServerWorkers = &service
+	}
+	return *container.ServerWorkers, nil
+}
+
+// MustGetServerWorkers is defined in dingo.yml.
+func (container *Container) MustGetServerWorkers() int {
+	service, err := container.GetServerWorkers()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetSigner is defined in dingo.yml.
 func (container *Container) GetSigner(req *http.Request) *Signer {
 	return container.Signer(req)
 }
+
+// GetSomeEnv is defined in dingo.yml.
 func (container *Container) GetSomeEnv() string {
+	if container.parent != nil && container.SomeEnv == nil {
+		return container.parent.GetSomeEnv()
+	}
 	if container.SomeEnv == nil {
 		service := os.Getenv("ShouldBeSet")
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.SomeEnv = &service
 	}
 	return *container.SomeEnv
 }
+
+// GetStartupLog is defined in dingo.yml.
+func (container *Container) GetStartupLog() *StartupLog {
+	if container.parent != nil && container.StartupLog == nil {
+		return container.parent.GetStartupLog()
+	}
+	if container.StartupLog == nil {
+		service := &StartupLog{}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.StartupLog = service
+	}
+	return container.StartupLog
+}
+
+// GetStartupMailer is defined in dingo.yml.
+func (container *Container) GetStartupMailer() (*SendEmail, error) {
+	if container.parent != nil && container.StartupMailer == nil {
+		return container.parent.GetStartupMailer()
+	}
+	if container.StartupMailer == nil {
+		service, err := NewSendEmailFrom(os.Getenv("STARTUP_MAILER_FROM"))
+		if err != nil {
+			return nil, fmt.Errorf("StartupMailer: %w", err)
+		}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.StartupMailer = service
+	}
+	return container.StartupMailer, nil
+}
+
+// MustGetStartupMailer is defined in dingo.yml.
+func (container *Container) MustGetStartupMailer() *SendEmail {
+	service, err := container.GetStartupMailer()
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// GetThreadSafeSendEmail is defined in dingo.yml.
+func (container *Container) GetThreadSafeSendEmail() *SendEmail {
+	if container.parent != nil && container.ThreadSafeSendEmail == nil {
+		return container.parent.GetThreadSafeSendEmail()
+	}
+	container.mutexThreadSafeSendEmail.Lock()
+	defer container.mutexThreadSafeSendEmail.Unlock()
+	if container.ThreadSafeSendEmail == nil {
+		service, err := NewSendEmail()
+		if err != nil {
+			panic(err)
+		}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.ThreadSafeSendEmail = service
+	}
+	return container.ThreadSafeSendEmail
+}
+
+// GetTraced is defined in dingo.yml.
+func (container *Container) GetTraced() *Traced {
+	if container.parent != nil && container.Traced == nil {
+		return container.parent.GetTraced()
+	}
+	if container.Traced == nil {
+		service := &Traced{}
+		service.Clock = container.GetClock()
+		service.Retries = *new(int)
+		service.Tracer = nil
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.Traced = service
+	}
+	return container.Traced
+}
+
+// GetTransaction is defined in dingo.yml.
+func (container *Container) GetTransaction() *Transaction {
+	if container.Transaction == nil {
+		service := &Transaction{}
+		service.Connection = container.GetConnection()
+		service.Log = container.GetCloseLog()
+		container.addCloser(func() error {
+			return service.Rollback()
+		})
+		container.Transaction = service
+	}
+	return container.Transaction
+}
+
+// GetUserRepo is defined in dingo.yml.
+func (container *Container) GetUserRepo() UserRepo {
+	return container.GetLoggedUserRepo()
+}
+
+// innerUserRepo is defined in dingo.yml.
+func (container *Container) innerUserRepo() UserRepo {
+	if container.parent != nil && container.UserRepo == nil {
+		return container.parent.innerUserRepo()
+	}
+	if container.UserRepo == nil {
+		service := &MemoryUserRepo{}
+		service.Names = map[int]string{1: "Bob"}
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.UserRepo = service
+	}
+	return container.UserRepo
+}
+
+// GetUserService is defined in dingo.yml.
+func (container *Container) GetUserService() *UserService {
+	if container.parent != nil && container.UserService == nil {
+		return container.parent.GetUserService()
+	}
+	if container.UserService == nil {
+		service := &UserService{}
+		service.Repo = container.GetUserRepo()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
+		container.UserService = service
+	}
+	return container.UserService
+}
+
+// GetWhatsTheTime is defined in dingo.yml.
 func (container *Container) GetWhatsTheTime() *WhatsTheTime {
+	if container.parent != nil && container.WhatsTheTime == nil {
+		return container.parent.GetWhatsTheTime()
+	}
 	if container.WhatsTheTime == nil {
 		service := &WhatsTheTime{}
 		service.clock = container.GetClock()
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.WhatsTheTime = service
 	}
 	return container.WhatsTheTime
 }
+
+// GetWithEnv1 is defined in dingo.yml.
 func (container *Container) GetWithEnv1() SendEmail {
+	if container.parent != nil && container.WithEnv1 == nil {
+		return container.parent.GetWithEnv1()
+	}
 	if container.WithEnv1 == nil {
 		service := SendEmail{}
 		service.From = os.Getenv("ShouldBeSet")
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.WithEnv1 = &service
 	}
 	return *container.WithEnv1
 }
+
+// GetWithEnv2 is defined in dingo.yml.
 func (container *Container) GetWithEnv2() *SendEmail {
+	if container.parent != nil && container.WithEnv2 == nil {
+		return container.parent.GetWithEnv2()
+	}
 	if container.WithEnv2 == nil {
 		service := &SendEmail{}
 		service.From = "foo-" + os.Getenv("ShouldBeSet") + "-bar"
+		if closer, ok := interface{}(service).(io.Closer); ok {
+			container.addCloser(closer.Close)
+		}
 		container.WithEnv2 = service
 	}
 	return container.WithEnv2
 }
+func (container *Container) initParameters() {
+	container.parameterErrors = map[string]error{}
+	for _, name := range []string{"api_timeout", "base_url", "api_url"} {
+		container.parameterErrors[name] = container.initParameter(name)
+	}
+}
+func (container *Container) initParameter(name string) error {
+	switch name {
+	case "api_timeout":
+		envAPI_TIMEOUT, err := container.envDuration("API_TIMEOUT", "5s", false)
+		if err != nil {
+			return fmt.Errorf("parameter api_timeout: %w", err)
+		}
+		container.Parameters.APITimeout = envAPI_TIMEOUT
+	case "base_url":
+		envAPI_BASE_URL, _ := container.envString("API_BASE_URL", "https://example.com", false)
+		container.Parameters.BaseURL = envAPI_BASE_URL
+	case "api_url":
+		container.Parameters.APIURL = container.Parameters.BaseURL + "/api"
+	}
+	return nil
+}
+func (container *Container) envBool(name, defaultValue string, required bool) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok && required {
+		return false, fmt.Errorf("environment variable %s is not set", name)
+	}
+	if !ok {
+		value = defaultValue
+	}
+	if value == "" && required {
+		return false, fmt.Errorf("environment variable %s is empty", name)
+	}
+	if value == "" {
+		return false, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("environment variable %s: %w", name, err)
+	}
+	return result, nil
+}
+func (container *Container) envDuration(name, defaultValue string, required bool) (time.Duration, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok && required {
+		return 0, fmt.Errorf("environment variable %s is not set", name)
+	}
+	if !ok {
+		value = defaultValue
+	}
+	if value == "" && required {
+		return 0, fmt.Errorf("environment variable %s is empty", name)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s: %w", name, err)
+	}
+	return result, nil
+}
+func (container *Container) envInt(name, defaultValue string, required bool) (int, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok && required {
+		return 0, fmt.Errorf("environment variable %s is not set", name)
+	}
+	if !ok {
+		value = defaultValue
+	}
+	if value == "" && required {
+		return 0, fmt.Errorf("environment variable %s is empty", name)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s: %w", name, err)
+	}
+	return result, nil
+}
+func (container *Container) envString(name, defaultValue string, required bool) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok && required {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	if !ok {
+		value = defaultValue
+	}
+	return value, nil
+}
+func (container *Container) readFile(path, defaultValue string, required bool) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return defaultValue, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -1,11 +1,17 @@
 package dingotest_test
 
 import (
+	"context"
+	"errors"
 	"github.com/elliotchance/dingo/dingotest"
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -151,3 +157,684 @@ func TestContainer_GetWhatsTheTime(t *testing.T) {
 	actual := container.GetWhatsTheTime().InRFC1123()
 	assert.Equal(t, "Wed, 04 Apr 1984 00:00:00 UTC", actual)
 }
+
+func TestContainer_GetThreadSafeSendEmail(t *testing.T) {
+	// Run with -race to verify that the service is only created once.
+	container := dingotest.NewContainer()
+
+	services := make(chan *dingotest.SendEmail, 10)
+	for i := 0; i < cap(services); i++ {
+		go func() {
+			services <- container.GetThreadSafeSendEmail()
+		}()
+	}
+
+	service := <-services
+	for i := 1; i < cap(services); i++ {
+		assert.Exactly(t, service, <-services)
+	}
+}
+
+func TestContainer_GetCustomerWelcomeFrom(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		t.Setenv("SEND_EMAIL_FROM", "hi@welcome.com")
+		container := dingotest.NewContainer()
+
+		welcomer, err := container.GetCustomerWelcomeFrom()
+		require.NoError(t, err)
+		assert.Equal(t, "hi@welcome.com",
+			welcomer.Emailer.(*dingotest.SendEmail).From)
+		assert.Exactly(t, welcomer, container.MustGetCustomerWelcomeFrom())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Setenv("SEND_EMAIL_FROM", "")
+		container := dingotest.NewContainer()
+
+		welcomer, err := container.GetCustomerWelcomeFrom()
+		assert.Nil(t, welcomer)
+		assert.EqualError(t, err,
+			"CustomerWelcomeFrom: SendEmailFrom: from is required")
+		assert.Nil(t, container.CustomerWelcomeFrom)
+
+		assert.Panics(t, func() {
+			container.MustGetCustomerWelcomeFrom()
+		})
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		t.Setenv("SEND_EMAIL_FROM", "")
+		container := dingotest.NewContainer()
+		container.SendEmailFrom = &dingotest.SendEmail{From: "foo@bar.com"}
+
+		welcomer, err := container.GetCustomerWelcomeFrom()
+		require.NoError(t, err)
+		assert.Equal(t, "foo@bar.com",
+			welcomer.Emailer.(*dingotest.SendEmail).From)
+	})
+}
+
+func TestContainer_GetParsedTimeOrError(t *testing.T) {
+	container := dingotest.NewContainer()
+
+	t.Run("Success", func(t *testing.T) {
+		tm, err := container.GetParsedTimeOrError("02 Jan 06 15:04 MST")
+		require.NoError(t, err)
+		assert.Equal(t, "2006-01-02 15:04:00 +0000 MST", tm.String())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := container.GetParsedTimeOrError("bad format")
+		assert.EqualError(t, err, `ParsedTimeOrError: parsing time "bad format" as "02 Jan 06 15:04 MST": cannot parse "bad format" as "02"`)
+	})
+}
+
+func TestContainer_Close(t *testing.T) {
+	t.Run("ReverseOrder", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.GetConnectionPool()
+
+		err := container.Close()
+		assert.EqualError(t, err, "pool is already shut down")
+		assert.Equal(t, []string{"ConnectionPool", "Connection"},
+			container.GetCloseLog().Names)
+	})
+
+	t.Run("OnlyCreatedServices", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.GetConnection()
+
+		assert.NoError(t, container.Close())
+		assert.Equal(t, []string{"Connection"}, container.GetCloseLog().Names)
+	})
+
+	t.Run("OverriddenServicesAreNotClosed", func(t *testing.T) {
+		log := &dingotest.CloseLog{}
+		container := dingotest.NewContainer()
+		container.Connection = &dingotest.Connection{Log: log}
+		container.GetConnection()
+
+		assert.NoError(t, container.Close())
+		assert.Empty(t, log.Names)
+	})
+
+	t.Run("OnlyClosedOnce", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.GetConnection()
+
+		assert.NoError(t, container.Close())
+		assert.NoError(t, container.Close())
+		assert.Equal(t, []string{"Connection"}, container.GetCloseLog().Names)
+	})
+}
+
+func TestContainer_Start(t *testing.T) {
+	t.Run("DependencyOrder", func(t *testing.T) {
+		t.Setenv("STARTUP_MAILER_FROM", "bob@example.com")
+		container := dingotest.NewContainer()
+
+		require.NoError(t, container.Start(context.Background()))
+		assert.Equal(t, []string{"Database", "Cache"}, container.GetStartupLog().Names)
+		assert.True(t, container.Database.Connected)
+		assert.Equal(t, "bob@example.com", container.StartupMailer.From)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Setenv("STARTUP_MAILER_FROM", "")
+		container := dingotest.NewContainer()
+
+		err := container.Start(context.Background())
+		assert.EqualError(t, err, "StartupMailer: from is required")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		container := dingotest.NewContainer()
+
+		assert.Equal(t, context.Canceled, container.Start(ctx))
+		assert.Nil(t, container.Database)
+	})
+
+	t.Run("Init", func(t *testing.T) {
+		t.Setenv("STARTUP_MAILER_FROM", "bob@example.com")
+		container := dingotest.NewContainer()
+
+		require.NoError(t, container.Init())
+		assert.Equal(t, []string{"Database", "Cache"}, container.GetStartupLog().Names)
+	})
+}
+
+type contextKey struct{}
+
+func TestContainer_GetClientContext(t *testing.T) {
+	t.Run("PassedToDependencies", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), contextKey{}, "foo")
+		container := dingotest.NewContainer()
+
+		client, err := container.GetClientContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "foo", client.Dialer.Context.Value(contextKey{}))
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		container := dingotest.NewContainer()
+
+		_, err := container.GetClientContext(ctx)
+		assert.EqualError(t, err, "Client: Dialer: context canceled")
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Nil(t, container.Client)
+	})
+
+	t.Run("Background", func(t *testing.T) {
+		container := dingotest.NewContainer()
+
+		client, err := container.GetClient()
+		require.NoError(t, err)
+		assert.Equal(t, context.Background(), client.Dialer.Context)
+	})
+
+	t.Run("KeepsFirstContext", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), contextKey{}, "foo")
+		container := dingotest.NewContainer()
+
+		container.MustGetClientContext(ctx)
+		client := container.MustGetClientContext(context.Background())
+		assert.Equal(t, "foo", client.Dialer.Context.Value(contextKey{}))
+	})
+
+	t.Run("MustPanics", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		container := dingotest.NewContainer()
+
+		assert.Panics(t, func() {
+			container.MustGetClientContext(ctx)
+		})
+	})
+}
+
+func TestContainer_GetRequestContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "foo")
+	container := dingotest.NewContainer()
+
+	request, err := container.GetRequestContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", request.Context.Value(contextKey{}))
+	assert.True(t, container.MustGetDialer() == request.Dialer)
+}
+
+func TestContainer_NewScope(t *testing.T) {
+	t.Run("SharesContainerServices", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		scope := container.NewScope()
+
+		assert.True(t, container.GetConnection() == scope.GetConnection())
+		assert.True(t, container.GetConnection() == scope.GetTransaction().Connection)
+		assert.Nil(t, scope.Connection)
+	})
+
+	t.Run("OverridesContainerServices", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		scope := container.NewScope()
+		connection := &dingotest.Connection{}
+		scope.Connection = connection
+
+		assert.True(t, connection == scope.GetConnection())
+		assert.True(t, connection == scope.GetTransaction().Connection)
+		assert.False(t, connection == container.GetConnection())
+	})
+
+	t.Run("CachesRequestServices", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		scope1 := container.NewScope()
+		scope2 := container.NewScope()
+
+		assert.True(t, scope1.GetTransaction() == scope1.GetTransaction())
+		assert.False(t, scope1.GetTransaction() == scope2.GetTransaction())
+		assert.Nil(t, container.Transaction)
+	})
+
+	t.Run("CloseReleasesRequestServices", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		scope := container.NewScope()
+		scope.GetTransaction()
+
+		assert.NoError(t, scope.Close())
+		assert.Equal(t, []string{"Transaction"}, container.GetCloseLog().Names)
+
+		assert.NoError(t, container.Close())
+		assert.Equal(t, []string{"Transaction", "Connection"},
+			container.GetCloseLog().Names)
+	})
+
+	t.Run("UsesParentPrototypes", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.ParsedTime = func(value string) time.Time {
+			return time.Unix(0, 0)
+		}
+
+		assert.Equal(t, time.Unix(0, 0), container.NewScope().GetParsedTime("foo"))
+	})
+}
+
+func TestContainer_GetHealthCheck(t *testing.T) {
+	container := dingotest.NewContainer()
+	healthCheck := container.GetHealthCheck()
+
+	var names []string
+	for _, checker := range healthCheck.Checkers {
+		names = append(names, checker.(*dingotest.NamedHealthCheck).Name)
+	}
+
+	assert.Equal(t, []string{"cache", "database", "queue"}, names)
+	assert.Equal(t, map[string]dingotest.HealthChecker{
+		"CacheHealth":    container.GetCacheHealth(),
+		"DatabaseHealth": container.GetDatabaseHealth(),
+		"QueueHealth":    container.GetQueueHealth(),
+	}, healthCheck.ByName)
+	assert.Equal(t, healthCheck.Checkers,
+		container.GetHealthCheckPrototype().Checkers)
+}
+
+func TestContainer_GetUserRepo(t *testing.T) {
+	t.Run("Decorated", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		repo := container.GetUserService().Repo
+
+		assert.Equal(t, "Bob", repo.FindName(1))
+		assert.Equal(t, "Bob", repo.FindName(1))
+		assert.True(t, repo == container.GetUserRepo())
+		assert.Equal(t, []int{1, 1}, container.GetLoggedUserRepo().Calls)
+		assert.Equal(t, map[int]string{1: "Bob"}, container.GetCachedUserRepo().Cache)
+		assert.True(t, container.UserRepo == container.GetCachedUserRepo().Inner)
+	})
+
+	t.Run("OverrideService", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.UserRepo = &dingotest.MemoryUserRepo{
+			Names: map[int]string{1: "Jane"},
+		}
+
+		assert.Equal(t, "Jane", container.GetUserRepo().FindName(1))
+		assert.Equal(t, []int{1}, container.GetLoggedUserRepo().Calls)
+	})
+
+	t.Run("OverrideDecorator", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.LoggedUserRepo = &dingotest.LoggedUserRepo{
+			Inner: &dingotest.MemoryUserRepo{},
+		}
+
+		assert.True(t, container.LoggedUserRepo == container.GetUserRepo())
+		assert.Nil(t, container.CachedUserRepo)
+	})
+}
+
+func TestContainer_GetEmailSender(t *testing.T) {
+	t.Run("SameService", func(t *testing.T) {
+		container := dingotest.NewContainer()
+
+		assert.True(t, container.GetSendEmail() == container.GetEmailSender())
+		assert.True(t, container.GetSendEmail() ==
+			container.GetCustomerWelcomeFromAlias().Emailer)
+	})
+
+	t.Run("OverrideTarget", func(t *testing.T) {
+		emailer := &FakeEmailSender{}
+		container := dingotest.NewContainer()
+		container.SendEmail = emailer
+
+		assert.True(t, emailer == container.GetEmailSender())
+	})
+
+	t.Run("Arguments", func(t *testing.T) {
+		container := dingotest.NewContainer()
+
+		assert.Equal(t, container.GetParsedTime("02 Jan 06 15:04 MST"),
+			container.GetParseTime("02 Jan 06 15:04 MST"))
+	})
+
+	t.Run("ReturnsError", func(t *testing.T) {
+		t.Setenv("SEND_EMAIL_FROM", "")
+		container := dingotest.NewContainer()
+
+		_, err := container.GetSendEmailFromAlias()
+		assert.EqualError(t, err, "SendEmailFrom: from is required")
+	})
+}
+
+func TestContainer_Lazy(t *testing.T) {
+	t.Run("BreaksCycle", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		parent := container.GetParent()
+
+		assert.True(t, parent == parent.Child.Parent())
+	})
+
+	t.Run("NotCreatedUntilCalled", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.GetChild()
+
+		assert.Nil(t, container.Parent)
+	})
+
+	t.Run("Prototype", func(t *testing.T) {
+		t.Setenv("SEND_EMAIL_FROM", "")
+		container := dingotest.NewContainer()
+		report := container.GetReport()
+
+		t.Setenv("SEND_EMAIL_FROM", "bob@example.com")
+		mailer, err := report.Mailer()
+		require.NoError(t, err)
+		assert.Equal(t, "bob@example.com", mailer.From)
+	})
+}
+
+func TestContainer_GetTraced(t *testing.T) {
+	container := dingotest.NewContainer()
+	traced := container.GetTraced()
+
+	assert.Nil(t, traced.Tracer)
+	assert.Equal(t, 0, traced.Retries)
+	assert.True(t, container.GetClock() == traced.Clock)
+}
+
+func TestContainer_GetNotifier(t *testing.T) {
+	for env, expected := range map[string]string{
+		"":    "log: hello",
+		"sms": "sms: hello",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv("NOTIFIER", env)
+
+			container := dingotest.NewContainer()
+			assert.Equal(t, expected, container.GetNotifier().Notify("hello"))
+		})
+	}
+}
+
+func TestContainer_GetServerConfig(t *testing.T) {
+	for testName, test := range map[string]struct {
+		env      map[string]string
+		expected *dingotest.ServerConfig
+		err      string
+	}{
+		"Defaults": {
+			env: map[string]string{"SERVER_API_KEY": ""},
+			expected: &dingotest.ServerConfig{
+				Host:    "localhost",
+				Port:    8080,
+				Timeout: 5 * time.Second,
+			},
+		},
+		"Set": {
+			env: map[string]string{
+				"SERVER_API_KEY": "secret",
+				"SERVER_HOST":    "",
+				"SERVER_PORT":    "80",
+				"SERVER_TIMEOUT": "1m",
+				"SERVER_DEBUG":   "true",
+			},
+			expected: &dingotest.ServerConfig{
+				APIKey:  "secret",
+				Port:    80,
+				Timeout: time.Minute,
+				Debug:   true,
+			},
+		},
+		"MissingRequired": {
+			err: "ServerConfig: environment variable SERVER_API_KEY is not set",
+		},
+		"InvalidInt": {
+			env: map[string]string{"SERVER_API_KEY": "", "SERVER_PORT": "http"},
+			err: `ServerConfig: environment variable SERVER_PORT: strconv.Atoi: parsing "http": invalid syntax`,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			for _, name := range []string{"SERVER_API_KEY", "SERVER_HOST",
+				"SERVER_PORT", "SERVER_TIMEOUT", "SERVER_DEBUG"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			container := dingotest.NewContainer()
+			config, err := container.GetServerConfig()
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, config)
+			}
+		})
+	}
+}
+
+func TestContainer_GetServerAddress(t *testing.T) {
+	t.Setenv("SERVER_PORT", "9000")
+
+	address, err := dingotest.NewContainer().GetServerAddress()
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:9000", address)
+}
+
+func TestContainer_GetServerWorkers(t *testing.T) {
+	for testName, test := range map[string]struct {
+		value    *string
+		expected int
+		err      string
+	}{
+		"Set":     {value: stringPtr("4"), expected: 4},
+		"Empty":   {value: stringPtr(""), err: "ServerWorkers: environment variable SERVER_WORKERS is empty"},
+		"Missing": {err: "ServerWorkers: environment variable SERVER_WORKERS is not set"},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			t.Setenv("SERVER_WORKERS", "")
+			os.Unsetenv("SERVER_WORKERS")
+			if test.value != nil {
+				t.Setenv("SERVER_WORKERS", *test.value)
+			}
+
+			workers, err := dingotest.NewContainer().GetServerWorkers()
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, workers)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestContainer_Parameters(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		assert.Equal(t, dingotest.Parameters{
+			APITimeout: 5 * time.Second,
+			APIURL:     "https://example.com/api",
+			BaseURL:    "https://example.com",
+		}, container.Parameters)
+		assert.Equal(t, &dingotest.APIClient{
+			URL:     "https://example.com/api",
+			Timeout: 5 * time.Second,
+		}, container.MustGetAPIClient())
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("API_BASE_URL", "http://localhost")
+		t.Setenv("API_TIMEOUT", "1m")
+
+		client := dingotest.NewContainer().MustGetAPIClient()
+		assert.Equal(t, "http://localhost/api", client.URL)
+		assert.Equal(t, time.Minute, client.Timeout)
+	})
+
+	t.Run("InvalidEnv", func(t *testing.T) {
+		t.Setenv("API_TIMEOUT", "bogus")
+
+		// Only the services that use the parameter fail.
+		container := dingotest.NewContainer()
+		_, err := container.GetAPIClient()
+		assert.EqualError(t, err,
+			`APIClient: parameter api_timeout: environment variable API_TIMEOUT: time: invalid duration "bogus"`)
+		assert.Nil(t, container.APIClient)
+
+		_, err = container.NewScope().GetAPIClient()
+		assert.Error(t, err)
+
+		assert.Equal(t, "https://example.com/api/users",
+			container.GetAPIRequest("/users").URL)
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		container := dingotest.NewContainer()
+		container.Parameters.APIURL = "http://test/api"
+
+		assert.Equal(t, "http://test/api", container.MustGetAPIClient().URL)
+		assert.Equal(t, "http://test/api/users",
+			container.NewScope().GetAPIRequest("/users").URL)
+	})
+}
+
+func TestContainer_LoadConfig(t *testing.T) {
+	for testName, test := range map[string]struct {
+		config   string
+		expected dingotest.Parameters
+		err      string
+	}{
+		"Empty": {
+			config: ``,
+			expected: dingotest.Parameters{
+				APITimeout: 5 * time.Second,
+				APIURL:     "https://example.com/api",
+				BaseURL:    "https://example.com",
+			},
+		},
+		"Dependent": {
+			config: `{"base_url": "http://localhost", "api_timeout": "1m"}`,
+			expected: dingotest.Parameters{
+				APITimeout: time.Minute,
+				APIURL:     "http://localhost/api",
+				BaseURL:    "http://localhost",
+			},
+		},
+		"Overridden": {
+			config: `{"base_url": "http://localhost", "api_url": "http://localhost/v2"}`,
+			expected: dingotest.Parameters{
+				APITimeout: 5 * time.Second,
+				APIURL:     "http://localhost/v2",
+				BaseURL:    "http://localhost",
+			},
+		},
+		"UnknownParameter": {
+			config: `{"base_uri": "http://localhost"}`,
+			err:    "unknown parameter: base_uri",
+		},
+		"InvalidType": {
+			config: `{"base_url": 1}`,
+			err: "parameter base_url: json: cannot unmarshal number into " +
+				"Go value of type string",
+		},
+		"InvalidDuration": {
+			config: `{"api_timeout": "soon"}`,
+			err:    `parameter api_timeout: time: invalid duration "soon"`,
+		},
+		"Truncated": {
+			config: `{"base_url": "http://localhost"`,
+			err:    "unexpected EOF",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			container := dingotest.NewContainer()
+			err := container.LoadConfig(strings.NewReader(test.config))
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, container.Parameters)
+			}
+		})
+	}
+}
+
+func TestContainer_LoadConfigChanged(t *testing.T) {
+	// A parameter that has been changed is kept unless it is in the config,
+	// or it uses one that is.
+	container := dingotest.NewContainer()
+	container.Parameters.APITimeout = time.Second
+	container.Parameters.APIURL = "http://localhost/v1"
+	require.NoError(t, container.LoadConfig(strings.NewReader(`{"api_timeout": "2s"}`)))
+	assert.Equal(t, dingotest.Parameters{
+		APITimeout: 2 * time.Second,
+		APIURL:     "http://localhost/v1",
+		BaseURL:    "https://example.com",
+	}, container.Parameters)
+
+	// Nothing is changed if the config is not valid.
+	require.Error(t, container.LoadConfig(strings.NewReader(
+		`{"base_url": "http://localhost", "api_timeout": "soon"}`)))
+	assert.Equal(t, "https://example.com", container.Parameters.BaseURL)
+}
+
+func TestContainer_LoadConfigEnv(t *testing.T) {
+	// A parameter in the config replaces the environment variable, including
+	// its error.
+	t.Setenv("API_TIMEOUT", "bogus")
+	container := dingotest.NewContainer()
+	_, err := container.GetAPIClient()
+	require.Error(t, err)
+
+	require.NoError(t, container.LoadConfig(strings.NewReader(`{"api_timeout": "3s"}`)))
+	client, err := container.GetAPIClient()
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Second, client.Timeout)
+}
+
+func TestContainer_NewScopeParameters(t *testing.T) {
+	container := dingotest.NewContainer()
+	require.NoError(t, container.LoadConfig(strings.NewReader(`{"api_timeout": "3s"}`)))
+
+	// The parameters are copied from the parent, the environment variable is
+	// not read again.
+	t.Setenv("API_TIMEOUT", "bogus")
+	scope := container.NewScope()
+	assert.Equal(t, 3*time.Second, scope.Parameters.APITimeout)
+}
+
+func TestNewContainerFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base_url": "http://localhost"}`), 0644))
+
+	container, err := dingotest.NewContainerFromConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/api", container.MustGetAPIClient().URL)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"api_timeout": "soon"}`), 0644))
+	_, err = dingotest.NewContainerFromConfig(path)
+	assert.EqualError(t, err, path+`: parameter api_timeout: time: invalid duration "soon"`)
+}
+
+func TestContainer_GetSecrets(t *testing.T) {
+	secrets, err := dingotest.NewContainer().GetSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, &dingotest.Secrets{
+		DatabasePassword: "hunter2",
+		APIToken:         "none",
+	}, secrets)
+
+	// The path is relative to the working directory of the test, which is
+	// the package directory.
+	_, err = dingotest.NewContainer().GetMissingSecret()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Contains(t, err.Error(), "MissingSecret: open testdata/missing: ")
+}
@@ -0,0 +1,23 @@
+package dingotest
+
+type HealthChecker interface {
+	Check() error
+}
+
+type NamedHealthCheck struct {
+	Name string
+}
+
+func (check *NamedHealthCheck) Check() error {
+	return nil
+}
+
+// HealthCheck runs all of the health checkers.
+type HealthCheck struct {
+	Checkers []HealthChecker
+	ByName   map[string]HealthChecker
+}
+
+func NewHealthCheck(checkers []HealthChecker) *HealthCheck {
+	return &HealthCheck{Checkers: checkers}
+}
@@ -0,0 +1,19 @@
+package dingotest
+
+// Parent and Child refer to each other. Child receives Parent lazily to break
+// the cycle.
+type Parent struct {
+	Child *Child
+}
+
+type Child struct {
+	Parent func() *Parent
+}
+
+type Report struct {
+	Mailer func() (*SendEmail, error)
+}
+
+func NewReport(mailer func() (*SendEmail, error)) *Report {
+	return &Report{Mailer: mailer}
+}
@@ -0,0 +1,17 @@
+package dingotest
+
+type Notifier interface {
+	Notify(message string) string
+}
+
+type SMSNotifier struct{}
+
+func (notifier *SMSNotifier) Notify(message string) string {
+	return "sms: " + message
+}
+
+type LogNotifier struct{}
+
+func (notifier *LogNotifier) Notify(message string) string {
+	return "log: " + message
+}
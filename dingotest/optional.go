@@ -0,0 +1,14 @@
+package dingotest
+
+import "github.com/jonboulle/clockwork"
+
+type Tracer interface {
+	Trace(name string)
+}
+
+// Traced has dependencies that are not configured in every binary.
+type Traced struct {
+	Tracer  Tracer
+	Retries int
+	Clock   clockwork.Clock
+}
@@ -0,0 +1,6 @@
+package dingotest
+
+type Secrets struct {
+	DatabasePassword string
+	APIToken         string
+}
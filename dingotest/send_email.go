@@ -1,5 +1,7 @@
 package dingotest
 
+import "errors"
+
 type SendEmail struct {
 	From string
 }
@@ -11,3 +13,11 @@ func (sender *SendEmail) Send(to, subject, body string) error {
 func NewSendEmail() (*SendEmail, error) {
 	return &SendEmail{}, nil
 }
+
+func NewSendEmailFrom(from string) (*SendEmail, error) {
+	if from == "" {
+		return nil, errors.New("from is required")
+	}
+
+	return &SendEmail{From: from}, nil
+}
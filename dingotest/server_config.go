@@ -0,0 +1,11 @@
+package dingotest
+
+import "time"
+
+type ServerConfig struct {
+	Host    string
+	Port    int
+	Timeout time.Duration
+	Debug   bool
+	APIKey  string
+}
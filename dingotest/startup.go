@@ -0,0 +1,25 @@
+package dingotest
+
+// StartupLog records the order that services are started.
+type StartupLog struct {
+	Names []string
+}
+
+func (log *StartupLog) Add(name string) {
+	log.Names = append(log.Names, name)
+}
+
+type Database struct {
+	Log       *StartupLog
+	Connected bool
+}
+
+func (db *Database) Connect() {
+	db.Connected = true
+	db.Log.Add("Database")
+}
+
+type Cache struct {
+	Database *Database
+	Log      *StartupLog
+}
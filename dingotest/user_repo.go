@@ -0,0 +1,50 @@
+package dingotest
+
+type UserRepo interface {
+	FindName(id int) string
+}
+
+type MemoryUserRepo struct {
+	Names map[int]string
+}
+
+func (repo *MemoryUserRepo) FindName(id int) string {
+	return repo.Names[id]
+}
+
+// CachedUserRepo decorates a UserRepo.
+type CachedUserRepo struct {
+	Inner UserRepo
+	Cache map[int]string
+}
+
+func NewCachedUserRepo(inner UserRepo) *CachedUserRepo {
+	return &CachedUserRepo{Inner: inner, Cache: map[int]string{}}
+}
+
+func (repo *CachedUserRepo) FindName(id int) string {
+	if name, ok := repo.Cache[id]; ok {
+		return name
+	}
+
+	name := repo.Inner.FindName(id)
+	repo.Cache[id] = name
+
+	return name
+}
+
+// LoggedUserRepo decorates a UserRepo.
+type LoggedUserRepo struct {
+	Inner UserRepo
+	Calls []int
+}
+
+func (repo *LoggedUserRepo) FindName(id int) string {
+	repo.Calls = append(repo.Calls, id)
+
+	return repo.Inner.FindName(id)
+}
+
+type UserService struct {
+	Repo UserRepo
+}
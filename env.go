@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// envType describes how an environment variable of a type is parsed.
+type envType struct {
+	goType string
+	zero   string
+
+	// parse is the expression that parses value. It returns the result, of
+	// goType, and an error. It is empty if value does not need to be parsed.
+	parse string
+
+	// importPath is needed by goType or parse.
+	importPath string
+
+	// check does the same as parse when generating, so that a default that
+	// cannot be parsed is reported. It is nil if parse is empty.
+	check func(value string) error
+}
+
+var envTypes = map[string]envType{
+	"string": {"string", `""`, "", "", nil},
+	"int": {"int", "0", "strconv.Atoi(value)", "strconv", func(value string) error {
+		_, err := strconv.Atoi(value)
+		return err
+	}},
+	"int64": {"int64", "0", "strconv.ParseInt(value, 10, 64)", "strconv", func(value string) error {
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err
+	}},
+	"float64": {"float64", "0", "strconv.ParseFloat(value, 64)", "strconv", func(value string) error {
+		_, err := strconv.ParseFloat(value, 64)
+		return err
+	}},
+	"bool": {"bool", "false", "strconv.ParseBool(value)", "strconv", func(value string) error {
+		_, err := strconv.ParseBool(value)
+		return err
+	}},
+	"duration": {"time.Duration", "0", "time.ParseDuration(value)", "time", func(value string) error {
+		_, err := time.ParseDuration(value)
+		return err
+	}},
+}
+
+// EnvReference is an environment variable in an expression, such as
+// "${DB_PORT:int=5432}" or "${API_KEY!}".
+type EnvReference struct {
+	Name string
+
+	// Type is one of the keys of envTypes. It is empty for a string.
+	Type string
+
+	// Default is used when the environment variable is not set.
+	Default    string
+	HasDefault bool
+
+	// Required returns an error if the environment variable is not set.
+	Required bool
+
+	// File is true for "${file:...}". Name is the path of a file that contains
+	// the value, such as a secret.
+	File bool
+}
+
+// parseEnvReference parses the contents of "${...}". The forms are "NAME",
+// "NAME!", "NAME:type", "NAME:type!", "NAME=default" and "NAME:type=default".
+func parseEnvReference(ref string) (*EnvReference, error) {
+	// The path of a file may contain ":", which would otherwise be a type.
+	if strings.HasPrefix(ref, fileReferencePrefix) {
+		return parseFileReference(strings.TrimPrefix(ref, fileReferencePrefix))
+	}
+
+	env := &EnvReference{}
+
+	if i := strings.Index(ref, "="); i >= 0 {
+		ref, env.Default, env.HasDefault = ref[:i], ref[i+1:], true
+	}
+
+	if strings.HasSuffix(ref, "!") {
+		ref, env.Required = strings.TrimSuffix(ref, "!"), true
+	}
+
+	if i := strings.Index(ref, ":"); i >= 0 {
+		ref, env.Type = ref[:i], ref[i+1:]
+		if _, ok := envTypes[env.Type]; !ok {
+			return nil, fmt.Errorf("unknown type for environment variable %s: %s",
+				ref, env.Type)
+		}
+	}
+
+	env.Name = ref
+	if !regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`).MatchString(env.Name) {
+		return nil, fmt.Errorf("invalid environment variable: %s", env.Name)
+	}
+
+	if env.Required && env.HasDefault {
+		return nil, fmt.Errorf("environment variable %s cannot be required and have a default",
+			env.Name)
+	}
+
+	// An empty default is the zero value.
+	if check := envTypes[env.envType()].check; check != nil && env.Default != "" {
+		if err := check(env.Default); err != nil {
+			return nil, fmt.Errorf("invalid default for environment variable %s: %v",
+				env.Name, err)
+		}
+	}
+
+	return env, nil
+}
+
+// IsPlain returns true for "${NAME}". It is the value of os.Getenv, so it is
+// empty if the environment variable is not set.
+func (env *EnvReference) IsPlain() bool {
+	return env.Type == "" && !env.Required && !env.HasDefault && !env.File
+}
+
+// CanFail returns true if the environment variable is required or has to be
+// parsed. Reading a file can always fail, even if it has a fallback.
+func (env *EnvReference) CanFail() bool {
+	return env.Required || env.File || envTypes[env.envType()].parse != ""
+}
+
+func (env *EnvReference) envType() string {
+	if env.Type == "" {
+		return "string"
+	}
+
+	return env.Type
+}
+
+// envFuncName is the Container method that looks up an environment variable of
+// a type, such as "envDuration".
+func envFuncName(ty string) string {
+	return "env" + strings.ToUpper(ty[:1]) + ty[1:]
+}
+
+// lookup is the call to the Container method that returns the value and an
+// error.
+func (env *EnvReference) lookup() string {
+	funcName := envFuncName(env.envType())
+	if env.File {
+		funcName = readFileFuncName
+	}
+
+	return fmt.Sprintf("container.%s(%q, %q, %t)", funcName, env.Name,
+		env.Default, env.Required)
+}
+
+// envArgumentName is the name of the local variable, or prototype function
+// argument, for an environment variable.
+func envArgumentName(ref string) string {
+	env, err := parseEnvReference(ref)
+	if err != nil {
+		return ""
+	}
+
+	if env.File {
+		return fileArgumentName(env.Name)
+	}
+
+	return "env" + env.Name
+}
+
+// EnvReferences returns the environment variables, such as "DB_PORT:int", that
+// are not plain. They are looked up by the getter before the service is
+// created.
+func (e Expression) EnvReferences() []string {
+	return e.findReferences(envReferenceRegexp, func(ref string) bool {
+		env, err := parseEnvReference(ref)
+
+		return err == nil && !env.IsPlain()
+	})
+}
+
+// envReferences returns the EnvReferences of every expression used to create
+// the service. The error statement is not included because it is not
+// substituted.
+func (service *Service) envReferences() (refs []string) {
+	refs = service.Returns.EnvReferences()
+	for _, property := range service.SortedProperties() {
+		refs = append(refs, property.Value.EnvReferences()...)
+	}
+
+	for _, hook := range service.OnInit {
+		refs = append(refs, hook.EnvReferences()...)
+	}
+
+	return uniqueSorted(refs)
+}
+
+// envCanFail returns true if looking up any of the environment variables can
+// fail. The getter returns the error.
+func (service *Service) envCanFail() bool {
+	for _, ref := range service.envReferences() {
+		if env, _ := parseEnvReference(ref); env.CanFail() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envDiagnostics returns a problem for each environment variable that cannot
+// be parsed, or that is used with different forms in the same service.
+func (service *Service) envDiagnostics(serviceName string, expr Expression, keys ...string) (diagnostics Diagnostics) {
+	for _, v := range envReferenceRegexp.FindAllStringSubmatch(string(expr), -1) {
+		env, err := parseEnvReference(v[1])
+		if err == nil && !env.IsPlain() {
+			for _, other := range service.envReferences() {
+				if other != v[1] && envArgumentName(other) == envArgumentName(v[1]) {
+					kind := "environment variable"
+					if env.File {
+						kind = "file"
+					}
+
+					err = fmt.Errorf("%s %s is used as both ${%s} and ${%s}",
+						kind, env.Name, v[1], other)
+				}
+			}
+		}
+
+		if err != nil {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     service.Position(keys...),
+				Service: serviceName,
+				Err:     err,
+			})
+		}
+	}
+
+	return
+}
+
+// envGoType is the Go type of an environment variable reference.
+func envGoType(ref string) string {
+	env, _ := parseEnvReference(ref)
+
+	return envTypes[env.envType()].goType
+}
+
+// astResolveEnv creates a local variable for each environment variable that is
+// not plain. If the lookup fails the error is returned by the getter. The
+// returned locals map each reference to its variable.
+func (service *Service) astResolveEnv(file *File, serviceName string) (stmts []ast.Stmt, locals map[string]string) {
+	locals = map[string]string{}
+	for _, ref := range service.envReferences() {
+		env, _ := parseEnvReference(ref)
+		local := envArgumentName(ref)
+		locals[envLocalKey(ref)] = local
+
+		call := newIdent(env.lookup())
+
+		if !env.CanFail() {
+			stmts = append(stmts, &ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent(local), newIdent("_")},
+				Rhs: []ast.Expr{call},
+			})
+			continue
+		}
+
+		stmts = append(stmts,
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent(local), newIdent("err")},
+				Rhs: []ast.Expr{call},
+			},
+			&ast.IfStmt{
+				Cond: newIdent("err != nil"),
+				Body: newBlock(service.astReturnError(file, serviceName)),
+			},
+		)
+	}
+
+	return
+}
+
+// envLocalKey is the key of an environment variable in the locals of
+// performSubstitutions. It cannot be confused with a service reference.
+func envLocalKey(ref string) string {
+	return "$" + ref
+}
+
+// addImport adds the import if the package is not already imported, with any
+// name.
+func (file *File) addImport(path string) {
+	for _, spec := range file.file.Imports {
+		if importPath, _ := strconv.Unquote(spec.Path.Value); importPath == path {
+			return
+		}
+	}
+
+	astutil.AddImport(file.fset, file.file, path)
+}
+
+// astEnvFuncs creates a Container method for each type of environment variable
+// that is used by a service or parameter. The method looks up the environment
+// variable, so that an empty value can be told apart from one that is not set.
+// If any files are read, the method that reads them is also created.
+func (file *File) astEnvFuncs() (decls []ast.Decl) {
+	var refs []string
+	for _, service := range file.Services {
+		if service != nil {
+			refs = append(refs, service.envReferences()...)
+		}
+	}
+
+	for _, parameter := range file.Parameters {
+		if parameter != nil {
+			refs = append(refs, parameter.Value.EnvReferences()...)
+		}
+	}
+
+	types := map[string]bool{}
+	readsFiles := false
+	for _, ref := range refs {
+		env, _ := parseEnvReference(ref)
+		if env.File {
+			readsFiles = true
+		} else {
+			types[env.envType()] = true
+		}
+	}
+
+	var typeNames []string
+	for ty := range types {
+		typeNames = append(typeNames, ty)
+	}
+	sort.Strings(typeNames)
+
+	for _, ty := range typeNames {
+		file.addImport("fmt")
+		file.addImport("os")
+
+		envType := envTypes[ty]
+		if envType.importPath != "" {
+			file.addImport(envType.importPath)
+		}
+
+		stmts := []ast.Stmt{
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("value"), newIdent("ok")},
+				Rhs: []ast.Expr{newIdent("os.LookupEnv(name)")},
+			},
+			&ast.IfStmt{
+				Cond: newIdent("!ok && required"),
+				Body: newBlock(newReturn(newIdent(envType.zero),
+					newIdent(`fmt.Errorf("environment variable %s is not set", name)`))),
+			},
+			&ast.IfStmt{
+				Cond: newIdent("!ok"),
+				Body: newBlock(&ast.AssignStmt{
+					Tok: token.ASSIGN,
+					Lhs: []ast.Expr{newIdent("value")},
+					Rhs: []ast.Expr{newIdent("defaultValue")},
+				}),
+			},
+		}
+
+		if envType.parse == "" {
+			stmts = append(stmts, newReturn(newIdent("value"), newIdent("nil")))
+		} else {
+			stmts = append(stmts,
+				&ast.IfStmt{
+					Cond: newIdent(`value == "" && required`),
+					Body: newBlock(newReturn(newIdent(envType.zero),
+						newIdent(`fmt.Errorf("environment variable %s is empty", name)`))),
+				},
+				&ast.IfStmt{
+					Cond: newIdent(`value == ""`),
+					Body: newBlock(newReturn(newIdent(envType.zero), newIdent("nil"))),
+				},
+				&ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("result"), newIdent("err")},
+					Rhs: []ast.Expr{newIdent(envType.parse)},
+				},
+				&ast.IfStmt{
+					Cond: newIdent("err != nil"),
+					Body: newBlock(newReturn(newIdent(envType.zero),
+						newIdent(`fmt.Errorf("environment variable %s: %w", name, err)`))),
+				},
+				newReturn(newIdent("result"), newIdent("nil")),
+			)
+		}
+
+		decls = append(decls, &ast.FuncDecl{
+			Name: newIdent(envFuncName(ty)),
+			Recv: newReceiver(),
+			Type: &ast.FuncType{
+				Params:  newFieldList("name, defaultValue string", "required bool"),
+				Results: newFieldList(envType.goType, "error"),
+			},
+			Body: newBlock(stmts...),
+		})
+	}
+
+	if readsFiles {
+		decls = append(decls, file.astReadFileFunc())
+	}
+
+	return
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseEnvReference(t *testing.T) {
+	for ref, test := range map[string]struct {
+		expected *EnvReference
+		err      string
+	}{
+		"DB_PORT": {
+			expected: &EnvReference{Name: "DB_PORT"},
+		},
+		"DB_PORT:int": {
+			expected: &EnvReference{Name: "DB_PORT", Type: "int"},
+		},
+		"TIMEOUT:duration=5s": {
+			expected: &EnvReference{Name: "TIMEOUT", Type: "duration",
+				Default: "5s", HasDefault: true},
+		},
+		"HOST=http://localhost:8080": {
+			expected: &EnvReference{Name: "HOST",
+				Default: "http://localhost:8080", HasDefault: true},
+		},
+		"API_KEY!": {
+			expected: &EnvReference{Name: "API_KEY", Required: true},
+		},
+		"DEBUG:bool!": {
+			expected: &EnvReference{Name: "DEBUG", Type: "bool", Required: true},
+		},
+		"DB_PORT:uint": {
+			err: "unknown type for environment variable DB_PORT: uint",
+		},
+		"API_KEY!=foo": {
+			err: "environment variable API_KEY cannot be required and have a default",
+		},
+		"API KEY": {
+			err: "invalid environment variable: API KEY",
+		},
+		"PORT:int=abc": {
+			err: `invalid default for environment variable PORT: strconv.Atoi: parsing "abc": invalid syntax`,
+		},
+		"TIMEOUT:duration=5": {
+			err: `invalid default for environment variable TIMEOUT: time: missing unit in duration "5"`,
+		},
+		"PORT:int=": {
+			expected: &EnvReference{Name: "PORT", Type: "int", HasDefault: true},
+		},
+		"file:/run/secrets/db_password": {
+			expected: &EnvReference{Name: "/run/secrets/db_password",
+				Required: true, File: true},
+		},
+		"file:C:/secrets/token=dev:token": {
+			expected: &EnvReference{Name: "C:/secrets/token",
+				Default: "dev:token", HasDefault: true, File: true},
+		},
+		"file:=fallback": {
+			err: "file must have a path",
+		},
+	} {
+		t.Run(ref, func(t *testing.T) {
+			env, err := parseEnvReference(ref)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, env)
+			}
+		})
+	}
+}
+
+func TestEnvReference_CanFail(t *testing.T) {
+	for ref, expected := range map[string]bool{
+		"HOST":          false,
+		"HOST=local":    false,
+		"HOST!":         true,
+		"PORT:int":      true,
+		"NAME:string=a": false,
+		"file:/secret":  true,
+		"file:/secret=": true,
+	} {
+		t.Run(ref, func(t *testing.T) {
+			env, err := parseEnvReference(ref)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, env.CanFail())
+		})
+	}
+}
+
+func TestExpression_EnvReferences(t *testing.T) {
+	expr := Expression("NewA(${HOST}, ${PORT:int}, ${KEY!}, ${PORT:int}, ${BAD:uint})")
+
+	assert.Equal(t, []string{"KEY!", "PORT:int"}, expr.EnvReferences())
+}
+
+func TestEnvArgumentName(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"DB_PORT:int":                  "envDB_PORT",
+		"file:/run/secrets/db-pass":    "file_run_secrets_db_pass_c300fc6e",
+		"file:config/token.txt=secret": "file_config_token_txt_da56d2ab",
+		"file:/a-b":                    "file_a_b_f79c7246",
+		"file:/a_b":                    "file_a_b_20b5c07c",
+	} {
+		t.Run(ref, func(t *testing.T) {
+			assert.Equal(t, expected, envArgumentName(ref))
+		})
+	}
+}
+
+func TestServices_IsFallible_Env(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A", Returns: "NewA(${HOST=localhost})"},
+		"B": {Type: "*B", Returns: "NewB(${PORT:int})"},
+		"C": {Type: "*C", Returns: "NewC(@{B})"},
+		"D": {Type: "*D", Close: "@{D}.Close(${HOST!})"},
+	}
+
+	assert.False(t, services.IsFallible("A"))
+	assert.True(t, services.IsFallible("B"))
+	assert.True(t, services.IsFallible("C"))
+	assert.False(t, services.IsFallible("D"))
+	assert.EqualError(t, services["D"].ValidateClose(),
+		"close can only use plain environment variables")
+}
+
+func TestFile_ValidateEnv(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    returns: NewA(${PORT:uint})
+  C:
+    type: '*C'
+    returns: NewC(${PORT:int=abc})
+  B:
+    type: '*B'
+    returns: NewB(${PORT:int})
+    properties:
+      Port: ${PORT:int=80}
+`)
+
+	err := file.Validate()
+	if assert.IsType(t, Diagnostics{}, err) {
+		var actual []string
+		for _, diagnostic := range err.(Diagnostics) {
+			actual = append(actual, diagnostic.Error())
+		}
+
+		assert.Equal(t, []string{
+			file.path + ":4:5: A: unknown type for environment variable PORT: uint",
+			file.path + ":7:5: C: invalid default for environment variable PORT: strconv.Atoi: parsing \"abc\": invalid syntax",
+			file.path + ":10:5: B: environment variable PORT is used as both ${PORT:int} and ${PORT:int=80}",
+			file.path + ":12:7: B: environment variable PORT is used as both ${PORT:int=80} and ${PORT:int}",
+		}, actual)
+	}
+}
+
+func TestGenerateContainer_FilesWithSimilarPaths(t *testing.T) {
+	source := generateSource(t, `parameters:
+  secret:
+    type: string
+    value: ${file:/a-b} + ${file:/a_b}
+services:
+  A:
+    type: '*A'
+    returns: NewA(${file:/a-b}, ${file:/a_b})
+`)
+
+	assert.Contains(t, source, `file_a_b_f79c7246, err := container.readFile("/a-b", "", true)`)
+	assert.Contains(t, source, `file_a_b_20b5c07c, err := container.readFile("/a_b", "", true)`)
+	assert.Contains(t, source, "NewA(file_a_b_f79c7246, file_a_b_20b5c07c)")
+	assert.Contains(t, source,
+		"container.Parameters.Secret = file_a_b_f79c7246 + file_a_b_20b5c07c")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/elliotchance/pie/pie"
@@ -12,29 +13,91 @@ import (
 
 type Expression string
 
-func (e Expression) DependencyNames() (deps []string) {
-	for _, v := range regexp.MustCompile(`@{(.*?)}`).FindAllStringSubmatch(string(e), -1) {
-		parts := strings.Split(v[1], "(")
-		deps = append(deps, parts[0])
+var (
+	// serviceReferenceRegexp matches "@{...}" and envReferenceRegexp matches
+	// "${...}". The first group is the reference.
+	serviceReferenceRegexp = regexp.MustCompile(`@{(.*?)}`)
+	envReferenceRegexp     = regexp.MustCompile(`\${(.*?)}`)
+)
+
+// uniqueSorted returns the values sorted and without duplicates. The arguments
+// of generated functions are in the order of the references they are for, so
+// the order must be the same every time the references are found.
+func uniqueSorted(values []string) []string {
+	values = pie.Strings(values).Unique()
+	sort.Strings(values)
+
+	return values
+}
+
+// findReferences returns each reference matched by re, such as "lazy:Mailer"
+// for "@{lazy:Mailer}", that keep returns true for. See uniqueSorted.
+func (e Expression) findReferences(re *regexp.Regexp, keep func(ref string) bool) []string {
+	var refs []string
+	for _, v := range re.FindAllStringSubmatch(string(e), -1) {
+		if keep(v[1]) {
+			refs = append(refs, v[1])
+		}
 	}
 
-	return pie.Strings(deps).Unique()
+	return uniqueSorted(refs)
 }
 
-func (e Expression) Dependencies() (deps []string) {
-	for _, v := range regexp.MustCompile(`@{(.*?)}`).FindAllStringSubmatch(string(e), -1) {
-		deps = append(deps, v[1])
+// isPlaceholder returns true if the reference is not to a service, such as
+// "@{ctx}", or is not always a dependency, such as "@{lazy:Mailer}" or
+// "@{?Tracer}".
+func isPlaceholder(ref string) bool {
+	_, _, isTag := parseTagReference(ref)
+	_, isLazy := parseLazyReference(ref)
+	_, _, isOptional := parseOptionalReference(ref)
+
+	return isTag || isLazy || isOptional || ref == ContextReference ||
+		ref == InnerReference
+}
+
+func (e Expression) DependencyNames() (deps []string) {
+	for _, dep := range e.Dependencies() {
+		deps = append(deps, strings.Split(dep, "(")[0])
 	}
 
-	return pie.Strings(deps).Unique()
+	return uniqueSorted(deps)
+}
+
+func (e Expression) Dependencies() (deps []string) {
+	return e.findReferences(serviceReferenceRegexp, func(ref string) bool {
+		return !isPlaceholder(ref)
+	})
 }
 
-func (e Expression) performSubstitutions(file *File, services Services, fromArgs bool) string {
+// performSubstitutions replaces parameters, environment variables and
+// references to services with Go code. If fromArgs is true the services and
+// parameters are arguments of the function being generated. Any references in
+// locals have already been resolved to the variable they map to. "@{ctx}" can
+// only be used when locals contains the context.
+func (e Expression) performSubstitutions(file *File, services Services, fromArgs bool, locals map[string]string) string {
 	stmt := string(e)
 
-	// Replace environment variables.
 	stmt = replaceAllStringSubmatchFunc(
-		regexp.MustCompile(`\${(.*?)}`), stmt, func(i []string) string {
+		parameterReferenceRegexp, stmt, func(i []string) string {
+			if fromArgs {
+				return parameterArgumentName(i[1])
+			}
+
+			return "container.Parameters." + parameterFieldName(i[1])
+		})
+
+	// Replace environment variables. Only plain environment variables are
+	// used directly, the others have already been looked up by the getter.
+	stmt = replaceAllStringSubmatchFunc(
+		envReferenceRegexp, stmt, func(i []string) string {
+			if local, ok := locals[envLocalKey(i[1])]; ok {
+				return local
+			}
+
+			if env, err := parseEnvReference(i[1]); err == nil && !env.IsPlain() && fromArgs {
+				return envArgumentName(i[1])
+			}
+
 			astutil.AddImport(file.fset, file.file, "os")
 
 			return fmt.Sprintf("os.Getenv(\"%s\")", i[1])
@@ -42,24 +105,59 @@ func (e Expression) performSubstitutions(file *File, services Services, fromArgs
 
 	// Replace service names.
 	stmt = replaceAllStringSubmatchFunc(
-		regexp.MustCompile(`@{(.*?)}`), stmt, func(i []string) string {
+		serviceReferenceRegexp, stmt, func(i []string) string {
+			ref := i[1]
+
+			// An optional service that exists is the same as any other
+			// reference.
+			if serviceName, ty, ok := parseOptionalReference(ref); ok {
+				if services[serviceName] == nil {
+					return file.optionalZeroValue(ty)
+				}
+
+				ref = serviceName
+			}
+
+			if local, ok := locals[ref]; ok {
+				return local
+			}
+
+			_, _, isTag := parseTagReference(ref)
+			_, isLazy := parseLazyReference(ref)
+			if fromArgs && (isTag || isLazy) {
+				return argumentName(ref)
+			}
+
 			if fromArgs {
-				return strings.Split(i[1], "(")[0]
+				return strings.Split(ref, "(")[0]
+			}
+
+			// The context is only available if the getter received one.
+			_, withContext := locals[ContextReference]
+			if isTag || isLazy {
+				return services.argumentExpression(ref, locals, withContext)
 			}
 
-			if strings.Contains(i[1], "(") {
-				return fmt.Sprintf("container.Get%s", i[1])
+			if strings.Contains(ref, "(") {
+				return services.getterCall(ref, withContext)
 			}
 
-			if _, existsService := services[i[1]]; !existsService {
-				panic(fmt.Sprintf("service does not exist: %s", i[1]))
+			// All references are checked by File.Validate before the
+			// container is generated.
+			service, existsService := services[ref]
+			if !existsService {
+				return fmt.Sprintf("container.Get%s()", ref)
 			}
 
-			if _, ok := services[i[1]].ContainerFieldType(services).(*ast.FuncType); ok {
-				return fmt.Sprintf("container.%s", i[1])
+			if _, ok := service.ContainerFieldType(services).(*ast.FuncType); ok {
+				if service.Alias != "" {
+					return fmt.Sprintf("container.%s", service.Alias)
+				}
+
+				return fmt.Sprintf("container.%s", ref)
 			}
 
-			return fmt.Sprintf("container.Get%s()", i[1])
+			return services.getterCall(ref, withContext)
 		})
 
 	return stmt
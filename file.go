@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"github.com/go-yaml/yaml"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"golang.org/x/tools/go/ast/astutil"
+	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
@@ -15,37 +18,263 @@ import (
 type File struct {
 	Package  string
 	Services Services
-	fset     *token.FileSet
-	file     *ast.File
+
+	// Parameters are values shared by services. Each parameter is a field of
+	// Container.Parameters that can be changed after the container is created.
+	Parameters Parameters
+
+	// Include is the paths of other files that contain services. They are
+	// resolved by ParseYAMLFile.
+	Include []string
+
+	// ThreadSafe synchronises the creation of all container scoped services.
+	// It can be overridden for each service.
+	ThreadSafe bool `yaml:"thread_safe"`
+
+	// profile and tags are used to select the alternatives of services with
+	// when. If profileTags is true the profile, and the other profiles that
+	// have an overlay, are also build tags.
+	profile     string
+	tags        []string
+	profileTags bool
+	profiles    []string
+
+	// yamlConfig decodes the config of LoadConfig as YAML instead of JSON.
+	yamlConfig bool
+
+	path string
+	fset *token.FileSet
+	file *ast.File
 }
 
-func ParseYAMLFile(filepath string) (*File, error) {
-	f, err := ioutil.ReadFile(filepath)
+// ParseYAMLFile reads the YAML file at filepath, and any files that it
+// includes. Each of the overlays is then merged onto it in order. See
+// mergeNodes.
+func ParseYAMLFile(filepath string, overlays ...string) (*File, error) {
+	root, err := readYAML(filepath)
 	if err != nil {
 		return nil, err
 	}
 
+	// paths contains the file that each node came from.
+	paths := map[*yaml.Node]string{}
+	locateNodes(root, filepath, paths)
+	err = resolveIncludes(filepath, root, paths, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, overlayPath := range overlays {
+		overlay, err := readYAML(overlayPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", overlayPath, err)
+		}
+
+		locateNodes(overlay, overlayPath, paths)
+		root = mergeNodes(root, overlay)
+	}
+
 	var all *File
-	err = yaml.Unmarshal(f, &all)
+	err = root.Decode(&all)
 	if err != nil {
 		return nil, err
 	}
+	if all == nil {
+		all = &File{}
+	}
+	all.path = filepath
 	all.fset = token.NewFileSet()
+	all.locateServices(filepath, root, paths)
+	all.locateParameters(filepath, root, paths)
 	return all, nil
 }
 
+func readYAML(path string) (*yaml.Node, error) {
+	f, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	err = yaml.Unmarshal(f, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &root, nil
+}
+
+// locateServices records where each service is defined in the YAML so that
+// problems can be reported with their location. Nodes in paths may be in a
+// different file to path, such as an included file or an overlay.
+func (file *File) locateServices(path string, root *yaml.Node, paths map[*yaml.Node]string) {
+	services := mappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i < len(services.Content)-1; i += 2 {
+		name, node := services.Content[i], services.Content[i+1]
+		if service, ok := file.Services[name.Value]; ok && service != nil {
+			service.path = path
+			service.paths = paths
+			service.name = name
+			service.node = node
+		}
+	}
+}
+
+// mappingValue returns the value for key if node is (or is a document that
+// contains) a mapping. Otherwise nil is returned.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// Validate checks all of the services. Every problem is returned as
+// Diagnostics, rather than stopping at the first one.
+// reservedNames are the members of the generated Container that are not
+// services. A service with one of these names would collide with them.
+var reservedNames = map[string]bool{
+	// Container.Close
+	"Close":        true,
+	"addCloser":    true,
+	"closers":      true,
+	"closersMutex": true,
+
+	// Container.Start
+	"Start": true,
+	"Init":  true,
+
+	// Container.NewScope
+	"NewScope": true,
+	"parent":   true,
+
+	// Container.Parameters
+	"Parameters":      true,
+	"parameterErrors": true,
+	"initParameters":  true,
+	"initParameter":   true,
+	"LoadConfig":      true,
+}
+
+func (file *File) Validate() error {
+	diagnostics := file.Parameters.Diagnostics()
+	for _, serviceName := range file.Services.ServiceNames() {
+		service := file.Services[serviceName]
+		if service == nil {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     token.Position{Filename: file.path},
+				Service: serviceName,
+				Err:     errors.New("service has no definition"),
+			})
+			continue
+		}
+
+		if reservedNames[serviceName] {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     service.Position(),
+				Service: serviceName,
+				Err:     errors.New("service name is reserved"),
+			})
+		}
+
+		diagnostics = append(diagnostics, service.Diagnostics(serviceName)...)
+		diagnostics = append(diagnostics,
+			service.referenceDiagnostics(serviceName, file.Services)...)
+		diagnostics = append(diagnostics,
+			file.Services.scopeDiagnostics(serviceName)...)
+		diagnostics = append(diagnostics,
+			service.decoratesDiagnostics(serviceName, file.Services)...)
+		diagnostics = append(diagnostics,
+			service.aliasDiagnostics(serviceName, file.Services)...)
+		diagnostics = append(diagnostics,
+			service.whenDiagnostics(serviceName, file.Services)...)
+		diagnostics = append(diagnostics,
+			service.parameterDiagnostics(serviceName, file.Parameters)...)
+	}
+
+	for _, cycle := range file.Services.Cycles() {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Pos:     file.Services[cycle[0]].Position(),
+			Service: cycle[0],
+			Err: fmt.Errorf("dependency cycle: %s",
+				strings.Join(cycle, " -> ")),
+		})
+	}
+
+	return diagnostics.Err()
+}
+
 func GenerateContainer(all *File, packageName string, outputFile string) (*File, error) {
-	var err error
+	err := all.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	packageLine := fmt.Sprintf("// Code generated by dingo; DO NOT EDIT\npackage %s", packageName)
+	if constraint := all.BuildConstraint(); constraint != "" {
+		packageLine = fmt.Sprintf("// Code generated by dingo; DO NOT EDIT\n\n//go:build %s\n\npackage %s",
+			constraint, packageName)
+	}
+
+	all.selectAlternatives()
+	for _, service := range all.Services {
+		if service != nil {
+			service.parameters = all.Parameters
+		}
+	}
+
 	all.file, err = parser.ParseFile(all.fset, outputFile, packageLine, parser.ParseComments)
 	if err != nil {
 		return nil, err
 	}
 
+	// go/printer only prints the comments of the file that have a position,
+	// and the generated declarations do not have positions. Without them it
+	// prints the doc comment of each node instead, such as Deprecated. The
+	// header becomes the doc comment of the file, and go/printer still
+	// separates a build constraint from the package clause.
+	all.file.Doc = &ast.CommentGroup{}
+	for _, group := range all.file.Comments {
+		all.file.Doc.List = append(all.file.Doc.List, group.List...)
+	}
+	all.file.Comments = nil
+
+	astutil.AddImport(all.fset, all.file, "sync")
+
+	if len(all.Parameters) > 0 {
+		for packageName, shortName := range all.Parameters.Imports() {
+			astutil.AddNamedImport(all.fset, all.file, shortName, packageName)
+		}
+
+		all.file.Decls = append(all.file.Decls, all.astParametersStruct())
+	}
+
 	all.file.Decls = append(all.file.Decls,
-		all.Services.astContainerStruct(),
+		all.Services.astContainerStruct(all),
 		all.Services.astDefaultContainer(),
 		all.astNewContainerFunc())
+	all.file.Decls = append(all.file.Decls, all.astConfigFuncs()...)
+	all.file.Decls = append(all.file.Decls,
+		all.astNewScopeFunc(),
+		all.astAddCloserFunc(),
+		all.astCloseFunc(),
+		all.astInitFunc(),
+		all.astStartFunc(all.Services))
 
 	for _, serviceName := range all.Services.ServiceNames() {
 		definition := all.Services[serviceName]
@@ -55,35 +284,153 @@ func GenerateContainer(all *File, packageName string, outputFile string) (*File,
 			astutil.AddNamedImport(all.fset, all.file, shortName, packageName)
 		}
 
-		all.file.Decls = append(all.file.Decls, &ast.FuncDecl{
-			Name: newIdent("Get" + serviceName),
-			Recv: &ast.FieldList{
-				List: []*ast.Field{
-					{
-						Names: []*ast.Ident{
-							newIdent("container"),
-						},
-						Type: newIdent("*Container"),
-					},
-				},
-			},
+		returnType := definition.InterfaceOrLocalEntityType(all.Services, false)
+		results := func(fallible bool) *ast.FieldList {
+			if fallible {
+				return newFieldList(returnType, "error")
+			}
+
+			return newFieldList(returnType)
+		}
+
+		if definition.Alias != "" {
+			all.file.Decls = append(all.file.Decls,
+				all.astAliasGetters(serviceName, results)...)
+			continue
+		}
+
+		if len(definition.When) > 0 {
+			all.file.Decls = append(all.file.Decls,
+				all.astWhenGetters(serviceName, results)...)
+			continue
+		}
+
+		// The getter of a decorated service returns the outermost decorator.
+		// The service itself is created by an unexported method that is
+		// injected into the innermost decorator.
+		getterName := "Get" + serviceName
+		if outer := all.Services.Decorated(serviceName); outer != serviceName {
+			all.file.Decls = append(all.file.Decls, all.astGetter(definition,
+				"Get"+serviceName, results(all.Services.IsFallible(serviceName)),
+				all.Services.UsesContext(serviceName),
+				newBlock(newReturn(newIdent(all.Services.getterCall(outer, true)))))...)
+			getterName = "inner" + serviceName
+		}
+
+		all.file.Decls = append(all.file.Decls, all.astGetter(definition,
+			getterName, results(all.Services.isFallible(serviceName, map[string]bool{})),
+			all.Services.usesContext(serviceName, map[string]bool{}),
+			definition.astFunctionBody(all, all.Services, serviceName, serviceName))...)
+
+		if all.Services.IsFallible(serviceName) {
+			all.file.Decls = append(all.file.Decls, all.astMustGetter(definition,
+				serviceName, results(false), all.Services.UsesContext(serviceName))...)
+		}
+	}
+
+	if len(all.Parameters) > 0 {
+		all.file.Decls = append(all.file.Decls, all.astInitParametersFuncs()...)
+	}
+
+	// The imports of the services must be added first, they may have a name
+	// that is used by the environment variable types.
+	all.file.Decls = append(all.file.Decls, all.astEnvFuncs()...)
+
+	all.astSourceComments()
+	ast.SortImports(all.fset, all.file)
+
+	return all, nil
+}
+
+// astGetter creates the getter method for a service. If the service uses a
+// context the body is used for the method with the "Context" suffix, and the
+// getter without a context calls it with context.Background().
+func (file *File) astGetter(definition *Service, name string, results *ast.FieldList, usesContext bool, body *ast.BlockStmt) []ast.Decl {
+	if !usesContext {
+		return []ast.Decl{&ast.FuncDecl{
+			Name: newIdent(name),
+			Recv: newReceiver(),
 			Type: &ast.FuncType{
 				Params:  definition.astArguments(),
-				Results: newFieldList(definition.InterfaceOrLocalEntityType(all.Services, false)),
+				Results: results,
+			},
+			Body: body,
+		}}
+	}
+
+	return []ast.Decl{&ast.FuncDecl{
+		Name: newIdent(name + "Context"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: append(newFieldList(ContextReference+" context.Context").List,
+					definition.astArguments().List...),
 			},
-			Body: definition.astFunctionBody(all, all.Services, serviceName, serviceName),
+			Results: results,
+		},
+		Body: body,
+	}, &ast.FuncDecl{
+		Name: newIdent(name),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  definition.astArguments(),
+			Results: results,
+		},
+		Body: newBlock(newReturn(newIdent(fmt.Sprintf(
+			"container.%sContext(%s)", name,
+			strings.Join(append([]string{"context.Background()"},
+				definition.Arguments.Names()...), ", "))))),
+	}}
+}
+
+// astMustGetter creates the MustGet method for a service that returns an error.
+// It panics with the error instead. If the service uses a context there is also
+// a method with the "Context" suffix.
+func (file *File) astMustGetter(definition *Service, serviceName string, results *ast.FieldList, usesContext bool) []ast.Decl {
+	decls := []ast.Decl{&ast.FuncDecl{
+		Name: newIdent("MustGet" + serviceName),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  definition.astArguments(),
+			Results: results,
+		},
+		Body: definition.astMustFunctionBody(serviceName, false),
+	}}
+
+	if usesContext {
+		decls = append(decls, &ast.FuncDecl{
+			Name: newIdent("MustGet" + serviceName + "Context"),
+			Recv: newReceiver(),
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{
+					List: append(newFieldList(ContextReference+" context.Context").List,
+						definition.astArguments().List...),
+				},
+				Results: results,
+			},
+			Body: definition.astMustFunctionBody(serviceName, true),
 		})
 	}
 
-	ast.SortImports(all.fset, all.file)
+	return decls
+}
 
-	return all, nil
+// Source returns the formatted Go source of a container that has been created
+// with GenerateContainer.
+func (file *File) Source() ([]byte, error) {
+	var buf bytes.Buffer
+	err := format.Node(&buf, file.fset, file.file)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
 }
 
-func (file *File) getPackageName(dingoYMLPath string) string {
+func (file *File) getPackageName(dingoYMLPath string) (string, error) {
 	abs, err := filepath.Abs(dingoYMLPath)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	// The directory name is not enough because it may contain a command
@@ -92,7 +439,7 @@ func (file *File) getPackageName(dingoYMLPath string) string {
 	dir := filepath.Dir(abs)
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	for _, fileInfo := range files {
@@ -100,20 +447,20 @@ func (file *File) getPackageName(dingoYMLPath string) string {
 			!strings.HasSuffix(fileInfo.Name(), "_test.go") {
 			f, err := ioutil.ReadFile(dir + "/" + fileInfo.Name())
 			if err != nil {
-				panic(err)
+				return "", err
 			}
 
-			parsedFile, err := parser.ParseFile(file.fset, fileInfo.Name(), f, 0)
+			parsedFile, err := parser.ParseFile(file.fset, fileInfo.Name(), f, parser.PackageClauseOnly)
 			if err != nil {
-				panic(err)
+				return "", err
 			}
 
-			return parsedFile.Name.String()
+			return parsedFile.Name.String(), nil
 		}
 	}
 
 	// Couldn't find the package name. Assume command.
-	return "main"
+	return "main", nil
 }
 
 func (file *File) astNewContainerFunc() *ast.FuncDecl {
@@ -127,7 +474,18 @@ func (file *File) astNewContainerFunc() *ast.FuncDecl {
 		}
 	}
 
-	return newFunc("NewContainer", nil, []string{"*Container"}, newBlock(
+	return newFunc(file.newContainerFuncName(), nil, []string{"*Container"}, newBlock(
 		newReturn(newCompositeLit("&Container", fields)),
 	))
 }
+
+// newContainerFuncName is the function that creates the Container. The
+// parameters are set by NewContainer, or NewContainerFromConfig, after the
+// container is created. See astConfigFuncs.
+func (file *File) newContainerFuncName() string {
+	if len(file.Parameters) > 0 {
+		return "newContainer"
+	}
+
+	return "NewContainer"
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func parseYAML(t *testing.T, yml string) *File {
+	dir, err := ioutil.TempDir("", "dingo")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "dingo.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(yml), 0644))
+
+	file, err := ParseYAMLFile(path)
+	require.NoError(t, err)
+
+	return file
+}
+
+// generateSource returns the container generated from the YAML.
+func generateSource(t *testing.T, yml string) string {
+	file, err := GenerateContainer(parseYAML(t, yml), "main", "dingo.go")
+	require.NoError(t, err)
+
+	source, err := file.Source()
+	require.NoError(t, err)
+
+	return string(source)
+}
+
+func TestFile_Validate(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    scope: singleton
+  B:
+    type: int
+    scopee: container
+    error: panic(err)
+`)
+
+	err := file.Validate()
+	require.IsType(t, Diagnostics{}, err)
+
+	var actual []string
+	for _, diagnostic := range err.(Diagnostics) {
+		actual = append(actual, diagnostic.Error())
+	}
+
+	assert.Equal(t, []string{
+		file.path + ":4:5: A: invalid scope: singleton",
+		file.path + ":7:5: B: unknown key: scopee",
+		file.path + ":8:5: B: error cannot be used without returns",
+	}, actual)
+}
+
+func TestFile_ValidateNoErrors(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    scope: prototype
+`)
+
+	assert.NoError(t, file.Validate())
+}
+
+func TestFile_ValidateReferences(t *testing.T) {
+	file := parseYAML(t, `services:
+  SendEmail:
+    type: '*SendEmail'
+  A:
+    type: '*A'
+    returns: NewA(@{SendEmial})
+    properties:
+      Foo: '@{Database("foo")}'
+`)
+
+	err := file.Validate()
+	require.IsType(t, Diagnostics{}, err)
+
+	var actual []string
+	for _, diagnostic := range err.(Diagnostics) {
+		actual = append(actual, diagnostic.Error())
+	}
+
+	assert.Equal(t, []string{
+		file.path + ":6:5: A: service does not exist: SendEmial (did you mean SendEmail?)",
+		file.path + ":8:7: A: service does not exist: Database",
+	}, actual)
+}
+
+func TestFile_ValidateCycles(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    returns: NewA(@{B})
+  B:
+    type: '*B'
+    returns: NewB(@{A})
+`)
+
+	assert.EqualError(t, file.Validate(),
+		file.path+":2:3: A: dependency cycle: A -> B -> A")
+}
+
+func TestFile_ValidateReservedNames(t *testing.T) {
+	file := parseYAML(t, `services:
+  Close:
+    type: '*A'
+  closers:
+    type: '*B'
+`)
+
+	assert.EqualError(t, file.Validate(),
+		file.path+":2:3: Close: service name is reserved\n"+
+			file.path+":4:3: closers: service name is reserved")
+}
+
+func TestFile_ValidateScopes(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    returns: NewA(@{P()})
+  B:
+    type: '*B'
+    scope: request
+    returns: NewB(@{R})
+  P:
+    type: '*P'
+    scope: prototype
+    returns: NewP(@{R})
+  R:
+    type: '*R'
+    scope: request
+`)
+
+	assert.EqualError(t, file.Validate(),
+		file.path+":2:3: A: container scoped service cannot depend on request scoped service: R")
+}
+
+func TestFile_ValidateTags(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    returns: NewA(@{tagged:foo})
+  B:
+    type: '*B'
+    tags: [bar]
+    properties:
+      C: '@{tagged_map:bar}'
+`)
+
+	assert.EqualError(t, file.Validate(),
+		file.path+":4:5: A: no services are tagged: foo\n"+
+			file.path+":5:3: B: dependency cycle: B -> B")
+}
@@ -1,15 +1,32 @@
 module github.com/elliotchance/dingo
 
-go 1.12
+go 1.22.0
 
 require (
 	github.com/elliotchance/pie v1.34.0
 	github.com/elliotchance/testify-stats v1.0.0
-	github.com/go-yaml/yaml v2.1.0+incompatible
 	github.com/jonboulle/clockwork v0.1.0
-	github.com/kr/pretty v0.1.0 // indirect
-	github.com/stretchr/testify v1.3.0
-	golang.org/x/tools v0.0.0-20190530001615-b97706b7f64d
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
-	gopkg.in/yaml.v2 v2.2.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/stretchr/testify v1.4.0
+	golang.org/x/tools v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
+	k8s.io/apimachinery v0.18.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gogo/protobuf v1.3.1 // indirect
+	github.com/google/gofuzz v1.1.0 // indirect
+	github.com/json-iterator/go v1.1.8 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.1 // indirect
+	github.com/stretchr/objx v0.1.0 // indirect
+	golang.org/x/mod v0.21.0 // indirect
+	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
+	gopkg.in/yaml.v2 v2.2.8 // indirect
+	k8s.io/klog v1.0.0 // indirect
+	sigs.k8s.io/structured-merge-diff/v3 v3.0.0 // indirect
 )
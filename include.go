@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKeys are the root level keys that an included file can have.
+var includeKeys = map[string]bool{
+	"include":  true,
+	"services": true,
+}
+
+// nodePosition returns the location of node. Nodes in paths are in a different
+// file to path.
+func nodePosition(node *yaml.Node, path string, paths map[*yaml.Node]string) token.Position {
+	if nodePath, ok := paths[node]; ok {
+		path = nodePath
+	}
+
+	return token.Position{Filename: path, Line: node.Line, Column: node.Column}
+}
+
+// resolveIncludes adds the services of each file in the include list of root
+// to the services of root. The paths in the list are relative to the file that
+// includes them, and may be globs. Included files can also include other
+// files, each file is only included once. A glob that matches the file itself,
+// such as "*.yml", does not include it again.
+//
+// The nodes of each included file are added to paths so that problems can be
+// reported with their location.
+func resolveIncludes(path string, root *yaml.Node, paths map[*yaml.Node]string, seen map[string]bool) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	seen[abs] = true
+
+	include := mappingValue(root, "include")
+	if include == nil {
+		return nil
+	}
+
+	var patterns []string
+	if err := include.Decode(&patterns); err != nil {
+		return Diagnostics{{
+			Pos: nodePosition(include, path, paths),
+			Err: fmt.Errorf("include must be a list of paths"),
+		}}
+	}
+
+	services := mappingValue(root, "services")
+	if services == nil {
+		services = &yaml.Node{Kind: yaml.MappingNode}
+		mapping := root
+		if root.Kind == yaml.DocumentNode {
+			mapping = root.Content[0]
+		}
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "services"}, services)
+	}
+
+	var diagnostics Diagnostics
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), pattern))
+		if err != nil {
+			return err
+		}
+
+		if len(matches) == 0 {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos: nodePosition(include, path, paths),
+				Err: fmt.Errorf("include does not match any files: %s", pattern),
+			})
+		}
+
+		for _, match := range matches {
+			abs, err := filepath.Abs(match)
+			if err != nil {
+				return err
+			}
+
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+
+			included, err := readYAML(match)
+			if err != nil {
+				return fmt.Errorf("%s: %v", match, err)
+			}
+
+			locateNodes(included, match, paths)
+			err = resolveIncludes(match, included, paths, seen)
+			if d, ok := err.(Diagnostics); ok {
+				diagnostics = append(diagnostics, d...)
+			} else if err != nil {
+				return err
+			}
+
+			diagnostics = append(diagnostics,
+				mergeIncludedServices(services, included, match, path, paths)...)
+		}
+	}
+
+	return diagnostics.Err()
+}
+
+// mergeIncludedServices adds the services in included to services. A service
+// that is already defined is reported with both locations.
+func mergeIncludedServices(services, included *yaml.Node, includedPath, path string, paths map[*yaml.Node]string) (diagnostics Diagnostics) {
+	mapping := included
+	if mapping.Kind == yaml.DocumentNode && len(mapping.Content) > 0 {
+		mapping = mapping.Content[0]
+	}
+
+	if mapping.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if key := mapping.Content[i]; !includeKeys[key.Value] {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos: nodePosition(key, includedPath, paths),
+				Err: fmt.Errorf("included file cannot have %s", key.Value),
+			})
+		}
+	}
+
+	includedServices := mappingValue(mapping, "services")
+	if includedServices == nil || includedServices.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i < len(includedServices.Content)-1; i += 2 {
+		name := includedServices.Content[i]
+		if existing := mappingKey(services, name.Value); existing != nil {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     nodePosition(name, includedPath, paths),
+				Service: name.Value,
+				Err: fmt.Errorf("service is also defined at %s",
+					nodePosition(existing, path, paths)),
+			})
+			continue
+		}
+
+		services.Content = append(services.Content, name,
+			includedServices.Content[i+1])
+	}
+
+	return
+}
+
+// mappingKey returns the key node in a mapping, or nil if the key does not
+// exist.
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+
+	return nil
+}
+
+// astSourceComments adds a doc comment to each method of a service that names
+// the file the service is defined in. They are only added if the config file
+// includes other files.
+func (file *File) astSourceComments() {
+	if len(file.Include) == 0 {
+		return
+	}
+
+	for _, decl := range file.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil {
+			continue
+		}
+
+		serviceName := file.serviceForNode(fn)
+		if serviceName == "" {
+			continue
+		}
+
+		path := file.Services[serviceName].Position().Filename
+		if rel, err := filepath.Rel(filepath.Dir(file.path), path); err == nil {
+			path = rel
+		}
+
+		comments := []*ast.Comment{{
+			Text: fmt.Sprintf("// %s is defined in %s.", fn.Name.Name, filepath.ToSlash(path)),
+		}}
+		if fn.Doc != nil {
+			comments = append(append(comments, &ast.Comment{Text: "//"}),
+				fn.Doc.List...)
+		}
+
+		fn.Doc = &ast.CommentGroup{List: comments}
+	}
+}
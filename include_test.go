@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseYAMLFile_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `include:
+  - services/*.yml
+services:
+  A:
+    type: '*A'
+`,
+		"services/b.yml": `include:
+  - nested/c.yml
+services:
+  B:
+    type: '*B'
+`,
+		"services/nested/c.yml": `services:
+  C:
+    type: '*C'
+`,
+		"services/d.yml": `services:
+  D:
+    type: '*D'
+`,
+	})
+
+	file, err := ParseYAMLFile(filepath.Join(dir, "dingo.yml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"A", "B", "C", "D"}, file.Services.ServiceNames())
+	for serviceName, expected := range map[string]string{
+		"A": "dingo.yml:4:3",
+		"B": "services/b.yml:4:3",
+		"C": "services/nested/c.yml:2:3",
+		"D": "services/d.yml:2:3",
+	} {
+		t.Run(serviceName, func(t *testing.T) {
+			assert.Equal(t, filepath.Join(dir, expected),
+				file.Services[serviceName].Position().String())
+		})
+	}
+}
+
+func TestParseYAMLFile_IncludeItself(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `include:
+  - '*.yml'
+services:
+  A:
+    type: '*A'
+`,
+		"b.yml": `services:
+  B:
+    type: '*B'
+`,
+	})
+
+	file, err := ParseYAMLFile(filepath.Join(dir, "dingo.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B"}, file.Services.ServiceNames())
+}
+
+func TestParseYAMLFile_IncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `include:
+  - a.yml
+  - missing/*.yml
+services:
+  A:
+    type: '*A'
+`,
+		"a.yml": `package: foo
+services:
+  A:
+    type: '*A'
+  B:
+    type: '*B'
+`,
+	})
+
+	_, err := ParseYAMLFile(filepath.Join(dir, "dingo.yml"))
+	require.IsType(t, Diagnostics{}, err)
+
+	var actual []string
+	for _, diagnostic := range err.(Diagnostics) {
+		actual = append(actual, diagnostic.Error())
+	}
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.yml") + ":1:1: included file cannot have package",
+		filepath.Join(dir, "a.yml") + ":3:3: A: service is also defined at " +
+			filepath.Join(dir, "dingo.yml") + ":5:3",
+		filepath.Join(dir, "dingo.yml") + ":2:3: include does not match any files: missing/*.yml",
+	}, actual)
+}
+
+func TestGenerate_IncludeComments(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `package: foo
+include:
+  - services/b.yml
+services:
+  A:
+    type: int
+    returns: 1
+`,
+		"services/b.yml": `services:
+  B:
+    alias: A
+    deprecated: Use GetA instead.
+`,
+	})
+
+	opts := Options{Dir: dir, Config: "dingo.yml", Out: "dingo.go"}
+	require.NoError(t, generate(opts))
+
+	source, err := ioutil.ReadFile(opts.OutPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "\n// GetA is defined in dingo.yml.\n"+
+		"func (container *Container) GetA() int {\n")
+	assert.Contains(t, string(source), "\n// GetB is defined in services/b.yml.\n"+
+		"//\n"+
+		"// Deprecated: Use GetA instead.\n"+
+		"func (container *Container) GetB() int {\n")
+}
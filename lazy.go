@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LazyPrefix is used to inject a function that returns a service, such as
+// "@{lazy:Mailer}". The service is not created until the function is called, so
+// it is not a dependency of the service it is injected into.
+const LazyPrefix = "lazy:"
+
+// parseLazyReference returns the service name of a reference such as
+// "lazy:Mailer". ok is false if the reference is not lazy.
+func parseLazyReference(ref string) (serviceName string, ok bool) {
+	if strings.HasPrefix(ref, LazyPrefix) {
+		return strings.TrimPrefix(ref, LazyPrefix), true
+	}
+
+	return "", false
+}
+
+// LazyReferences returns the lazy references, such as "lazy:Mailer". They are
+// not included in Dependencies.
+func (e Expression) LazyReferences() []string {
+	return e.findReferences(serviceReferenceRegexp, func(ref string) bool {
+		_, ok := parseLazyReference(ref)
+
+		return ok
+	})
+}
+
+// lazyType is the function type injected for a lazy reference, such as
+// "func() Mailer".
+func (services Services) lazyType(ref string) string {
+	serviceName, _ := parseLazyReference(ref)
+	ty := services[serviceName].InterfaceOrLocalEntityType(services, false)
+	if services.IsFallible(serviceName) {
+		return fmt.Sprintf("func() (%s, error)", ty)
+	}
+
+	return "func() " + ty
+}
+
+// lazyArgumentName is the name of the prototype function argument that
+// receives the lazy function.
+func lazyArgumentName(ref string) string {
+	serviceName, _ := parseLazyReference(ref)
+
+	return "lazy" + serviceName
+}
+
+// lazyExpression creates the function literal for a lazy reference. The
+// context is not used because the function is called after the getter has
+// returned.
+func (services Services) lazyExpression(ref string) string {
+	serviceName, _ := parseLazyReference(ref)
+
+	return fmt.Sprintf("%s { return %s }", services.lazyType(ref),
+		services.getterCall(serviceName, false))
+}
+
+// lazyError returns a problem if the service of a lazy reference does not
+// exist, or cannot be created without arguments.
+func (services Services) lazyError(ref string) error {
+	serviceName, _ := parseLazyReference(ref)
+	service := services[serviceName]
+	if service == nil {
+		return unknownNameError("service", serviceName, services.ServiceNames())
+	}
+
+	if target := services[service.Alias]; target != nil {
+		service = target
+	}
+
+	if len(service.Arguments) > 0 {
+		return fmt.Errorf("lazy cannot be used with a service that has arguments: %s",
+			serviceName)
+	}
+
+	return nil
+}
+
+// ArgumentReferences returns the tag and lazy references. They are passed to
+// the function of a prototype as arguments because the function cannot use the
+// container.
+func (e Expression) ArgumentReferences() []string {
+	return append(e.TagReferences(), e.LazyReferences()...)
+}
+
+// argumentName is the name of the prototype function argument for a tag or
+// lazy reference.
+func argumentName(ref string) string {
+	if _, ok := parseLazyReference(ref); ok {
+		return lazyArgumentName(ref)
+	}
+
+	return tagArgumentName(ref)
+}
+
+// argumentType is the Go type of a tag or lazy reference.
+func (services Services) argumentType(ref string) string {
+	if _, ok := parseLazyReference(ref); ok {
+		return services.lazyType(ref)
+	}
+
+	return services.tagCollectionType(ref)
+}
+
+// argumentExpression creates the value for a tag or lazy reference.
+func (services Services) argumentExpression(ref string, locals map[string]string, withContext bool) string {
+	if _, ok := parseLazyReference(ref); ok {
+		return services.lazyExpression(ref)
+	}
+
+	return services.tagExpression(ref, locals, withContext)
+}
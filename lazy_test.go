@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServices_Dependencies_Lazy(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A", Properties: map[string]Expression{"B": "@{B}"}},
+		"B": {Type: "*B", Returns: "NewB(@{lazy:A})"},
+	}
+
+	assert.Equal(t, []string{"B"}, services.Dependencies("A"))
+	assert.Nil(t, services.Dependencies("B"))
+	assert.Nil(t, services.Cycles())
+}
+
+func TestServices_lazyExpression(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A"},
+		"B": {Type: "B", Interface: "io.Reader", Returns: "NewB()", ReturnsError: true},
+	}
+
+	assert.Equal(t, "func() *A { return container.GetA() }",
+		services.lazyExpression("lazy:A"))
+	assert.Equal(t, "func() (io.Reader, error) { return container.GetB() }",
+		services.lazyExpression("lazy:B"))
+}
+
+func TestServices_lazyError(t *testing.T) {
+	services := Services{
+		"Mailer": {Type: "*Mailer"},
+		"P":      {Type: "*P", Arguments: Arguments{"i": "int"}},
+	}
+
+	assert.NoError(t, services.lazyError("lazy:Mailer"))
+	assert.Equal(t, errors.New("service does not exist: Mailr (did you mean Mailer?)"),
+		services.lazyError("lazy:Mailr"))
+	assert.Equal(t, errors.New("lazy cannot be used with a service that has arguments: P"),
+		services.lazyError("lazy:P"))
+}
+
+func TestExpression_LazyReferences(t *testing.T) {
+	expr := Expression("NewP(@{lazy:C}, @{A}, @{lazy:A}, @{lazy:B}, @{lazy:C})")
+
+	assert.Equal(t, []string{"lazy:A", "lazy:B", "lazy:C"}, expr.LazyReferences())
+}
+
+func TestGenerateContainer_LazyReferences(t *testing.T) {
+	source := generateSource(t, `services:
+  A:
+    type: '*A'
+  B:
+    type: '*B'
+  C:
+    type: '*C'
+  P:
+    type: '*P'
+    scope: prototype
+    returns: NewP(@{lazy:C}, @{lazy:A}, @{lazy:B})
+`)
+
+	// The field, the function and the getter must agree on the order.
+	assert.Contains(t, source,
+		" func(lazyA func() *A, lazyB func() *B, lazyC func() *C) *P\n")
+	assert.Contains(t, source,
+		"P: func(lazyA func() *A, lazyB func() *B, lazyC func() *C) *P {\n")
+	assert.Contains(t, source, "return container.P(func() *A { return container.GetA() }, "+
+		"func() *B { return container.GetB() }, func() *C { return container.GetC() })\n")
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// CloseDisabled can be used as the close expression to prevent a service from
+// being closed, even if it implements io.Closer.
+const CloseDisabled = "false"
+
+// astCloser registers the service that has just been created so that it will be
+// closed by Container.Close. If the service does not have a close expression it
+// will be closed if it implements io.Closer.
+func (service *Service) astCloser(file *File, services Services, serviceName string) ast.Stmt {
+	if service.Close != "" {
+		closeExpr := service.Close.performSubstitutions(file, services, false,
+			map[string]string{serviceName: "service"})
+
+		return &ast.ExprStmt{
+			X: &ast.CallExpr{
+				Fun: newIdent("container.addCloser"),
+				Args: []ast.Expr{
+					&ast.FuncLit{
+						Type: &ast.FuncType{
+							Params:  newFieldList(),
+							Results: newFieldList("error"),
+						},
+						Body: newBlock(newReturn(newIdent(closeExpr))),
+					},
+				},
+			},
+		}
+	}
+
+	astutil.AddImport(file.fset, file.file, "io")
+
+	return &ast.IfStmt{
+		Init: &ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent("closer"), newIdent("ok")},
+			Rhs: []ast.Expr{newIdent("interface{}(service).(io.Closer)")},
+		},
+		Cond: newIdent("ok"),
+		Body: newBlock(&ast.ExprStmt{X: newIdent("container.addCloser(closer.Close)")}),
+	}
+}
+
+// astAddCloserFunc creates the method used by getters to register a service to
+// be closed.
+func (file *File) astAddCloserFunc() *ast.FuncDecl {
+	return &ast.FuncDecl{
+		Name: newIdent("addCloser"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList("closer func() error"),
+			Results: newFieldList(),
+		},
+		Body: newBlock(
+			&ast.ExprStmt{X: newIdent("container.closersMutex.Lock()")},
+			&ast.DeferStmt{Call: &ast.CallExpr{Fun: newIdent("container.closersMutex.Unlock")}},
+			&ast.AssignStmt{
+				Tok: token.ASSIGN,
+				Lhs: []ast.Expr{newIdent("container.closers")},
+				Rhs: []ast.Expr{newIdent("append(container.closers, closer)")},
+			},
+		),
+	}
+}
+
+// astCloseFunc creates Container.Close. It closes the services that were
+// created by the container in the reverse order that they were created. All of
+// the services are closed, even if some of them return an error.
+func (file *File) astCloseFunc() *ast.FuncDecl {
+	astutil.AddImport(file.fset, file.file, "errors")
+	astutil.AddImport(file.fset, file.file, "strings")
+
+	return &ast.FuncDecl{
+		Name: newIdent("Close"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList(),
+			Results: newFieldList("error"),
+		},
+		Body: newBlock(
+			&ast.ExprStmt{X: newIdent("container.closersMutex.Lock()")},
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("closers")},
+				Rhs: []ast.Expr{newIdent("container.closers")},
+			},
+			&ast.AssignStmt{
+				Tok: token.ASSIGN,
+				Lhs: []ast.Expr{newIdent("container.closers")},
+				Rhs: []ast.Expr{newIdent("nil")},
+			},
+			&ast.ExprStmt{X: newIdent("container.closersMutex.Unlock()")},
+			&ast.DeclStmt{
+				Decl: &ast.GenDecl{
+					Tok: token.VAR,
+					Specs: []ast.Spec{
+						&ast.ValueSpec{
+							Names: []*ast.Ident{newIdent("errs")},
+							Type:  newIdent("[]string"),
+						},
+					},
+				},
+			},
+			&ast.ForStmt{
+				Init: &ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("i")},
+					Rhs: []ast.Expr{newIdent("len(closers) - 1")},
+				},
+				Cond: newIdent("i >= 0"),
+				Post: &ast.IncDecStmt{X: newIdent("i"), Tok: token.DEC},
+				Body: newBlock(&ast.IfStmt{
+					Init: &ast.AssignStmt{
+						Tok: token.DEFINE,
+						Lhs: []ast.Expr{newIdent("err")},
+						Rhs: []ast.Expr{newIdent("closers[i]()")},
+					},
+					Cond: newIdent("err != nil"),
+					Body: newBlock(&ast.AssignStmt{
+						Tok: token.ASSIGN,
+						Lhs: []ast.Expr{newIdent("errs")},
+						Rhs: []ast.Expr{newIdent("append(errs, err.Error())")},
+					}),
+				}),
+			},
+			&ast.IfStmt{
+				Cond: newIdent("len(errs) > 0"),
+				Body: newBlock(newReturn(newIdent(`errors.New(strings.Join(errs, "; "))`))),
+			},
+			newReturn(newIdent("nil")),
+		),
+	}
+}
+
+// eagerServiceNames returns the services that are created by Container.Start,
+// in the order that they must be created.
+func (services Services) eagerServiceNames() (serviceNames []string) {
+	for _, serviceName := range services.DependencyOrder() {
+		if services[serviceName].Eager {
+			serviceNames = append(serviceNames, serviceName)
+		}
+	}
+
+	return
+}
+
+// astInitFunc creates Container.Init, which is Container.Start without a
+// context.
+func (file *File) astInitFunc() *ast.FuncDecl {
+	astutil.AddImport(file.fset, file.file, "context")
+
+	return &ast.FuncDecl{
+		Name: newIdent("Init"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList(),
+			Results: newFieldList("error"),
+		},
+		Body: newBlock(newReturn(newIdent("container.Start(context.Background())"))),
+	}
+}
+
+// astStartFunc creates Container.Start. It creates each of the eager services
+// so that configuration problems are found when the application starts rather
+// than when the service is first used. Services are created in dependency order
+// and the first error is returned. A panic while creating a service is also
+// returned as an error that names the service.
+func (file *File) astStartFunc(services Services) *ast.FuncDecl {
+	astutil.AddImport(file.fset, file.file, "context")
+
+	var stmts []ast.Stmt
+	serviceNames := services.eagerServiceNames()
+	if len(serviceNames) > 0 {
+		astutil.AddImport(file.fset, file.file, "fmt")
+
+		stmts = append(stmts,
+			&ast.DeclStmt{
+				Decl: &ast.GenDecl{
+					Tok: token.VAR,
+					Specs: []ast.Spec{
+						&ast.ValueSpec{
+							Names: []*ast.Ident{newIdent("serviceName")},
+							Type:  newIdent("string"),
+						},
+					},
+				},
+			},
+			&ast.DeferStmt{
+				Call: &ast.CallExpr{
+					Fun: &ast.FuncLit{
+						Type: &ast.FuncType{Params: newFieldList()},
+						Body: newBlock(&ast.IfStmt{
+							Init: &ast.AssignStmt{
+								Tok: token.DEFINE,
+								Lhs: []ast.Expr{newIdent("r")},
+								Rhs: []ast.Expr{newIdent("recover()")},
+							},
+							Cond: newIdent("r != nil"),
+							Body: newBlock(&ast.AssignStmt{
+								Tok: token.ASSIGN,
+								Lhs: []ast.Expr{newIdent("err")},
+								Rhs: []ast.Expr{newIdent(`fmt.Errorf("%s: %v", serviceName, r)`)},
+							}),
+						}),
+					},
+				},
+			},
+		)
+	}
+
+	for _, serviceName := range serviceNames {
+		stmts = append(stmts,
+			&ast.AssignStmt{
+				Tok: token.ASSIGN,
+				Lhs: []ast.Expr{newIdent("serviceName")},
+				Rhs: []ast.Expr{newIdent(fmt.Sprintf("%q", serviceName))},
+			},
+			&ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("err")},
+					Rhs: []ast.Expr{newIdent("ctx.Err()")},
+				},
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent("err"))),
+			},
+		)
+
+		getter := services.getterCall(serviceName, true)
+		if services.IsFallible(serviceName) {
+			// The getter already wraps the error with the service name.
+			stmts = append(stmts, &ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("_"), newIdent("err")},
+					Rhs: []ast.Expr{newIdent(getter)},
+				},
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent("err"))),
+			})
+		} else {
+			stmts = append(stmts, &ast.ExprStmt{X: newIdent(getter)})
+		}
+	}
+
+	stmts = append(stmts, newReturn(newIdent("nil")))
+
+	return &ast.FuncDecl{
+		Name: newIdent("Start"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params: newFieldList("ctx context.Context"),
+			Results: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{newIdent("err")}, Type: newIdent("error")},
+				},
+			},
+		},
+		Body: newBlock(stmts...),
+	}
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
-	"go/printer"
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/elliotchance/pie/pie"
+	"github.com/pmezard/go-difflib/difflib"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 )
 
 func replaceAllStringSubmatchFunc(re *regexp.Regexp, str string, repl func([]string) string) string {
@@ -24,32 +33,366 @@ func replaceAllStringSubmatchFunc(re *regexp.Regexp, str string, repl func([]str
 	return result + str[lastIndex:]
 }
 
-func main() {
-	dingoYMLPath := "dingo.yml"
-	outputFile := "dingo.go"
+// Options describes a single container to be generated.
+type Options struct {
+	// Dir is the directory that Config and Out are relative to.
+	Dir string
+
+	// Config is the path to the YAML file, usually "dingo.yml".
+	Config string
+
+	// Out is the path of the Go file that will be written, usually "dingo.go".
+	Out string
+
+	// Package overrides the package name. If it is empty the package from the
+	// YAML file is used, otherwise it is detected from the Go files in the
+	// same directory.
+	Package string
+
+	// Check will not write Out. Instead it returns an error if Out is not the
+	// same as what would be generated.
+	Check bool
+
+	// TypeCheck will compile the generated file with the rest of the package
+	// before it is written.
+	TypeCheck bool
+
+	// Profile and Tags select the alternatives of services with when. The
+	// overlay for Profile is merged onto Config, if it exists.
+	Profile string
+	Tags    []string
+
+	// ProfileTags uses the profiles as build tags. The generated file requires
+	// the tag of its profile and excludes the tags of every other profile. The
+	// profile must have an overlay.
+	ProfileTags bool
+
+	// YAMLConfig decodes the config of the generated LoadConfig as YAML
+	// instead of JSON. The generated package then imports gopkg.in/yaml.v3.
+	YAMLConfig bool
+}
+
+func (opts Options) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(opts.Dir, name)
+}
+
+// ConfigPath is the path to the YAML file, including Dir.
+func (opts Options) ConfigPath() string {
+	return opts.path(opts.Config)
+}
+
+// OutPath is the path to the generated Go file, including Dir.
+func (opts Options) OutPath() string {
+	return opts.path(opts.Out)
+}
+
+// ForPath returns a copy of the options for a path provided on the command
+// line. The path may be a directory containing the config file, or the path to
+// a YAML file itself.
+func (opts Options) ForPath(path string) Options {
+	if strings.HasSuffix(path, ".yml") || strings.HasSuffix(path, ".yaml") {
+		opts.Dir, opts.Config = filepath.Split(path)
+	} else {
+		opts.Dir = path
+	}
+
+	return opts
+}
+
+// parseOptions reads the config file, and the overlay for the profile if it
+// exists.
+func parseOptions(opts Options) (*File, error) {
+	dingoYMLPath := opts.ConfigPath()
+
+	var overlays []string
+	if opts.Profile != "" {
+		overlayPath := OverlayPath(dingoYMLPath, opts.Profile)
+		if _, err := os.Stat(overlayPath); err == nil {
+			overlays = append(overlays, overlayPath)
+		}
+	}
+
+	file, err := ParseYAMLFile(dingoYMLPath, overlays...)
+	if _, ok := err.(Diagnostics); ok {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", dingoYMLPath, err)
+	}
 
-	file, err := ParseYAMLFile(dingoYMLPath)
+	return file, nil
+}
+
+func generate(opts Options) error {
+	dingoYMLPath := opts.ConfigPath()
+	outputFile := opts.OutPath()
+
+	file, err := parseOptions(opts)
 	if err != nil {
-		log.Fatalln(err)
+		return err
+	}
+
+	file.profile, file.tags = opts.Profile, opts.Tags
+	file.profileTags = opts.ProfileTags
+	file.yamlConfig = opts.YAMLConfig
+	if opts.ProfileTags {
+		file.profiles, err = Profiles(dingoYMLPath)
+		if err != nil {
+			return fmt.Errorf("%s: profiles: %v", dingoYMLPath, err)
+		}
+
+		// The other files only exclude the profiles that have an overlay, so
+		// they would be built with this one.
+		if opts.Profile != "" && !pie.Strings(file.profiles).Contains(opts.Profile) {
+			return fmt.Errorf("%s: profile %s must have an overlay to be a build tag: %s",
+				dingoYMLPath, opts.Profile, OverlayPath(dingoYMLPath, opts.Profile))
+		}
 	}
 
-	packageName := file.Package
+	packageName := opts.Package
 	if packageName == "" {
-		packageName = file.getPackageName(dingoYMLPath)
+		packageName = file.Package
+	}
+	if packageName == "" {
+		packageName, err = file.getPackageName(dingoYMLPath)
+		if err != nil {
+			return fmt.Errorf("%s: package name: %v", dingoYMLPath, err)
+		}
 	}
 
 	file, err = GenerateContainer(file, packageName, outputFile)
+	if _, ok := err.(Diagnostics); ok {
+		// Diagnostics already include the path.
+		return err
+	}
 	if err != nil {
-		log.Fatalln(err)
+		return fmt.Errorf("%s: %v", dingoYMLPath, err)
 	}
 
-	outFile, err := os.Create(outputFile)
+	source, err := file.Source()
 	if err != nil {
-		log.Fatalln("open file:", err)
+		return fmt.Errorf("format: %v", err)
+	}
+
+	if opts.TypeCheck {
+		err = file.TypeCheck(outputFile, source)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = printer.Fprint(outFile, file.fset, file.file)
+	if opts.Check {
+		return check(outputFile, source)
+	}
+
+	err = ioutil.WriteFile(outputFile, source, 0644)
 	if err != nil {
-		log.Fatalln("writer:", err)
+		return fmt.Errorf("write file: %v", err)
+	}
+
+	return nil
+}
+
+// generateAll runs generate for each of the options in parallel. The returned
+// errors are in the same order as the options. Options that were successful
+// are not included.
+func generateAll(allOpts []Options) (errs []error) {
+	results := make([]error, len(allOpts))
+	limit := make(chan struct{}, runtime.NumCPU())
+
+	var wg sync.WaitGroup
+	for i, opts := range allOpts {
+		wg.Add(1)
+		go func(i int, opts Options) {
+			defer wg.Done()
+
+			limit <- struct{}{}
+			results[i] = generateRecovered(opts)
+			<-limit
+		}(i, opts)
+	}
+	wg.Wait()
+
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return
+}
+
+// generateRecovered runs generate and returns a panic as an error, so that one
+// container cannot stop the others from being generated.
+func generateRecovered(opts Options) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", opts.ConfigPath(), r)
+		}
+	}()
+
+	return generate(opts)
+}
+
+// findAll returns the options for every config file found in root or any of
+// its subdirectories. Hidden directories, "vendor", "testdata" and directories
+// that belong to a different module are skipped, in the same way as the go
+// tool treats "./...". A relative root is relative to Dir.
+func (opts Options) findAll(root string) (found []Options, err error) {
+	root = opts.path(root)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			if info.Name() == filepath.Base(opts.Config) {
+				opts := opts
+				opts.Dir, opts.Config = filepath.Dir(path), info.Name()
+				found = append(found, opts)
+			}
+
+			return nil
+		}
+
+		if path == root {
+			return nil
+		}
+
+		name := info.Name()
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			name == "vendor" || name == "testdata" {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// check returns an error containing a unified diff if the file at path does
+// not contain source.
+func check(path string, source []byte) error {
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read file: %v", err)
+	}
+
+	if bytes.Equal(existing, source) {
+		return nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(string(source)),
+		FromFile: path,
+		ToFile:   path + " (generated)",
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%s is out of date, run dingo to regenerate it:\n%s",
+		path, diff)
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config-schema" {
+		if err := runConfigSchema(os.Args[2:], os.Stdout); err != nil && err != flag.ErrHelp {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
+	var opts Options
+
+	flag.StringVar(&opts.Dir, "dir", ".",
+		"Directory that -config and -out are relative to.")
+	flag.StringVar(&opts.Config, "config", "dingo.yml",
+		"Path to the YAML file that describes the services.")
+	flag.StringVar(&opts.Out, "out", "dingo.go",
+		"Path to the Go file that will be generated.")
+	flag.BoolVar(&opts.Check, "check", false,
+		"Do not write any files. Exit with an error and print a diff if the\n"+
+			"generated file is out of date.")
+	flag.BoolVar(&opts.TypeCheck, "typecheck", true,
+		"Type check the generated file with the rest of the package before it\n"+
+			"is written.")
+	flag.StringVar(&opts.Package, "package", "",
+		"Package name for the generated file. Defaults to the package in the\n"+
+			"config file, or the package of the Go files next to it.")
+	flag.StringVar(&opts.Profile, "profile", "",
+		"Profile to generate. The overlay for the profile, such as\n"+
+			"dingo.dev.yml, is merged onto the config file. It is also used to\n"+
+			"select the alternatives of services with when.")
+	flag.BoolVar(&opts.ProfileTags, "profile-tags", false,
+		"Use profiles as build tags. The generated file requires the tag of\n"+
+			"its profile and excludes the tags of every other profile.")
+	flag.BoolVar(&opts.YAMLConfig, "yaml-config", false,
+		"Load the config of NewContainerFromConfig and LoadConfig as YAML\n"+
+			"instead of JSON. The generated package imports gopkg.in/yaml.v3.")
+	tags := flag.String("tags", "",
+		"Comma-separated build tags used to select the alternatives of\n"+
+			"services with when. The generated file has a build constraint for\n"+
+			"each tag that is used.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: dingo [flags] [path ...]\n"+
+				"       dingo config-schema [flags] [path]\n\n"+
+				"Each path is a directory containing the config file, or the\n"+
+				"path to a config file. If no paths are provided -dir is used.\n"+
+				"A path ending in \"/...\" generates every config file found in\n"+
+				"that directory and its subdirectories.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *tags != "" {
+		opts.Tags = strings.Split(*tags, ",")
+	}
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{opts.Dir}
+	}
+
+	var allOpts []Options
+	for _, path := range paths {
+		if path == "..." || strings.HasSuffix(path, "/...") {
+			root := strings.TrimSuffix(strings.TrimSuffix(path, "..."), "/")
+			if root == "" {
+				root = "."
+			}
+
+			found, err := opts.findAll(root)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			allOpts = append(allOpts, found...)
+			continue
+		}
+
+		allOpts = append(allOpts, opts.ForPath(path))
+	}
+
+	errs := generateAll(allOpts)
+	for _, err := range errs {
+		log.Println(err)
+	}
+
+	if len(errs) > 0 {
+		log.Fatalf("%d of %d containers failed", len(errs), len(allOpts))
 	}
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptions_ForPath(t *testing.T) {
+	defaults := Options{
+		Dir:    ".",
+		Config: "dingo.yml",
+		Out:    "dingo.go",
+	}
+
+	for testName, test := range map[string]struct {
+		path       string
+		configPath string
+		outPath    string
+	}{
+		"CurrentDirectory": {
+			path:       ".",
+			configPath: "dingo.yml",
+			outPath:    "dingo.go",
+		},
+		"Directory": {
+			path:       "foo/bar",
+			configPath: "foo/bar/dingo.yml",
+			outPath:    "foo/bar/dingo.go",
+		},
+		"ConfigFile": {
+			path:       "foo/wiring.yml",
+			configPath: "foo/wiring.yml",
+			outPath:    "foo/dingo.go",
+		},
+		"ConfigFileWithoutDirectory": {
+			path:       "wiring.yaml",
+			configPath: "wiring.yaml",
+			outPath:    "dingo.go",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			opts := defaults.ForPath(test.path)
+			assert.Equal(t, test.configPath, opts.ConfigPath())
+			assert.Equal(t, test.outPath, opts.OutPath())
+		})
+	}
+}
+
+func TestOptions_OutPath(t *testing.T) {
+	opts := Options{Dir: "foo", Out: "/tmp/dingo.go"}
+	assert.Equal(t, "/tmp/dingo.go", opts.OutPath())
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dingo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dingo.go")
+	source := []byte("package foo\n\nvar A = 1\n")
+
+	t.Run("Missing", func(t *testing.T) {
+		assert.Error(t, check(path, source))
+	})
+
+	require.NoError(t, ioutil.WriteFile(path, source, 0644))
+
+	t.Run("UpToDate", func(t *testing.T) {
+		assert.NoError(t, check(path, source))
+	})
+
+	t.Run("OutOfDate", func(t *testing.T) {
+		err := check(path, []byte("package foo\n\nvar A = 2\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "-var A = 1\n+var A = 2\n")
+	})
+}
+
+func TestOptions_findAll(t *testing.T) {
+	root, err := ioutil.TempDir("", "dingo")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	for _, path := range []string{
+		"dingo.yml",
+		"a/dingo.yml",
+		"a/b/dingo.yml",
+		"a/b/other.yml",
+		"c/dingo.yml",
+		"c/go.mod",
+		"vendor/d/dingo.yml",
+		"testdata/dingo.yml",
+		".git/dingo.yml",
+	} {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	}
+
+	for testName, test := range map[string]struct {
+		opts Options
+		root string
+	}{
+		"Root":          {Options{Config: "dingo.yml", Out: "dingo.go"}, root},
+		"RelativeToDir": {Options{Dir: root, Config: "dingo.yml", Out: "dingo.go"}, "."},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			found, err := test.opts.findAll(test.root)
+			require.NoError(t, err)
+
+			var configPaths []string
+			for _, opts := range found {
+				configPaths = append(configPaths, opts.ConfigPath())
+			}
+
+			assert.Equal(t, []string{
+				filepath.Join(root, "a/b/dingo.yml"),
+				filepath.Join(root, "a/dingo.yml"),
+				filepath.Join(root, "dingo.yml"),
+			}, configPaths)
+		})
+	}
+}
+
+func TestGenerateAll(t *testing.T) {
+	root, err := ioutil.TempDir("", "dingo")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	for path, yml := range map[string]string{
+		"a/dingo.yml": "package: a\nservices:\n  A:\n    type: '*A'\n",
+		"b/dingo.yml": "package: b\nservices:\n  B:\n    type: '*B'\n    returns: NewB(@{Missing})\n",
+	} {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(yml), 0644))
+	}
+
+	found, err := Options{Config: "dingo.yml", Out: "dingo.go"}.findAll(root)
+	require.NoError(t, err)
+
+	// The container that fails does not stop the other from being generated.
+	errs := generateAll(found)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "service does not exist: Missing")
+	assert.FileExists(t, filepath.Join(root, "a/dingo.go"))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// OptionalPrefix is used for a service that may not exist, such as
+// "@{?Tracer}". If the service does not exist the zero value is injected. The
+// type of the zero value can be provided after a colon, such as
+// "@{?Retries:int}", otherwise it is nil.
+const OptionalPrefix = "?"
+
+// parseOptionalReference returns the service name and the declared type of a
+// reference such as "?Tracer:trace.Tracer". ok is false if the reference is not
+// optional.
+func parseOptionalReference(ref string) (serviceName string, ty Type, ok bool) {
+	if !strings.HasPrefix(ref, OptionalPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(ref, OptionalPrefix), ":", 2)
+	if len(parts) == 2 {
+		return parts[0], Type(parts[1]), true
+	}
+
+	return parts[0], "", true
+}
+
+// OptionalReferences returns the optional references, such as "?Tracer". They
+// are not included in Dependencies because the service may not exist.
+func (e Expression) OptionalReferences() []string {
+	return e.findReferences(serviceReferenceRegexp, func(ref string) bool {
+		_, _, ok := parseOptionalReference(ref)
+
+		return ok
+	})
+}
+
+// expressionDependencies returns the Dependencies of the expression, including
+// the optional services that exist.
+func (services Services) expressionDependencies(e Expression) []string {
+	deps := e.Dependencies()
+	for _, ref := range e.OptionalReferences() {
+		if serviceName, _, _ := parseOptionalReference(ref); services[serviceName] != nil {
+			deps = append(deps, serviceName)
+		}
+	}
+
+	return uniqueSorted(deps)
+}
+
+// expressionDependencyNames returns the DependencyNames of the expression,
+// including the optional services that exist.
+func (services Services) expressionDependencyNames(e Expression) []string {
+	var names []string
+	for _, dep := range services.expressionDependencies(e) {
+		names = append(names, strings.Split(dep, "(")[0])
+	}
+
+	return uniqueSorted(names)
+}
+
+// optionalZeroValue is the value injected for an optional service that does
+// not exist.
+func (file *File) optionalZeroValue(ty Type) string {
+	if ty == "" {
+		return "nil"
+	}
+
+	if ty.PackageName() != "" {
+		astutil.AddNamedImport(file.fset, file.file, ty.LocalPackageName(),
+			ty.PackageName())
+	}
+
+	return "*new(" + ty.LocalEntityType() + ")"
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseOptionalReference(t *testing.T) {
+	for ref, expected := range map[string]struct {
+		serviceName string
+		ty          Type
+		ok          bool
+	}{
+		"?Tracer":              {"Tracer", "", true},
+		"?Tracer:trace.Tracer": {"Tracer", "trace.Tracer", true},
+		"Tracer":               {"", "", false},
+	} {
+		t.Run(ref, func(t *testing.T) {
+			serviceName, ty, ok := parseOptionalReference(ref)
+			assert.Equal(t, expected.serviceName, serviceName)
+			assert.Equal(t, expected.ty, ty)
+			assert.Equal(t, expected.ok, ok)
+		})
+	}
+}
+
+func TestServices_Dependencies_Optional(t *testing.T) {
+	services := Services{
+		"A": {Type: "*A", Returns: "NewA(@{?B}, @{?Missing}, @{?C:int})"},
+		"B": {Type: "*B"},
+		"C": {Type: "int"},
+	}
+
+	assert.Equal(t, []string{"B", "C"}, services.Dependencies("A"))
+}
+
+func TestFile_ValidateOptional(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+    returns: NewA(@{?Missing}, @{?Retries:int})
+`)
+
+	assert.NoError(t, file.Validate())
+}
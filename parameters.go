@@ -0,0 +1,578 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parameter is a value that is shared by services, such as a base URL or a
+// timeout. It is referenced as "%{name}" in any expression.
+type Parameter struct {
+	Type Type
+
+	// Value is the default value. It can reference environment variables and
+	// other parameters, but not services.
+	Value Expression
+
+	// path, name and node are the location of the parameter in the YAML file.
+	path string
+	name *yaml.Node
+	node *yaml.Node
+}
+
+type Parameters map[string]*Parameter
+
+var parameterReferenceRegexp = regexp.MustCompile(`%{(.*?)}`)
+
+// Position returns the location of the parameter in the YAML file.
+func (parameter *Parameter) Position() token.Position {
+	if parameter.name == nil {
+		return token.Position{Filename: parameter.path}
+	}
+
+	return token.Position{
+		Filename: parameter.path,
+		Line:     parameter.name.Line,
+		Column:   parameter.name.Column,
+	}
+}
+
+// ParameterReferences returns the names of the parameters used in the
+// expression.
+func (e Expression) ParameterReferences() []string {
+	return e.findReferences(parameterReferenceRegexp, func(string) bool {
+		return true
+	})
+}
+
+// initialisms are written in upper case in field names, in the same way as
+// golint.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "QPS": true,
+	"RAM": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true,
+	"SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true,
+	"UI": true, "UID": true, "UUID": true, "URI": true, "URL": true,
+	"UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true,
+	"XSS": true,
+}
+
+// parameterFieldName is the name of the field on the Parameters struct, such as
+// "BaseURL" for "base_url".
+func parameterFieldName(name string) string {
+	var fieldName string
+	for _, part := range strings.Split(name, "_") {
+		switch {
+		case part == "":
+		case initialisms[strings.ToUpper(part)]:
+			fieldName += strings.ToUpper(part)
+		default:
+			fieldName += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return fieldName
+}
+
+// parameterArgumentName is the name of the prototype function argument for a
+// parameter.
+func parameterArgumentName(name string) string {
+	return "param" + parameterFieldName(name)
+}
+
+// parameterReferences returns the parameters used by every expression used to
+// create the service.
+func (service *Service) parameterReferences() (names []string) {
+	names = service.Returns.ParameterReferences()
+	for _, property := range service.SortedProperties() {
+		names = append(names, property.Value.ParameterReferences()...)
+	}
+
+	for _, hook := range service.OnInit {
+		names = append(names, hook.ParameterReferences()...)
+	}
+
+	return uniqueSorted(names)
+}
+
+// parametersCanFail returns true if any of the parameters used by the service
+// can fail. The getter returns the error instead of creating the service.
+func (service *Service) parametersCanFail() bool {
+	for _, name := range service.parameterReferences() {
+		if service.parameters.CanFail(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (parameters Parameters) Names() []string {
+	var names []string
+	for name := range parameters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// CanFail returns true if an environment variable used by the parameter, or by
+// a parameter it references, can fail.
+func (parameters Parameters) CanFail(name string) bool {
+	return parameters.canFail(name, map[string]bool{})
+}
+
+func (parameters Parameters) canFail(name string, seen map[string]bool) bool {
+	parameter := parameters[name]
+	if parameter == nil || seen[name] {
+		return false
+	}
+
+	for _, ref := range parameter.Value.EnvReferences() {
+		if env, err := parseEnvReference(ref); err == nil && env.CanFail() {
+			return true
+		}
+	}
+
+	seen[name] = true
+	for _, ref := range parameter.Value.ParameterReferences() {
+		if parameters.canFail(ref, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Order returns the names of the parameters sorted so that each parameter comes
+// after the parameters it references.
+func (parameters Parameters) Order() (names []string) {
+	added := map[string]bool{}
+
+	var add func(name string)
+	add = func(name string) {
+		if added[name] || parameters[name] == nil {
+			return
+		}
+
+		// Cycles are reported by Diagnostics, they must not recurse forever.
+		added[name] = true
+		for _, ref := range parameters[name].Value.ParameterReferences() {
+			add(ref)
+		}
+
+		names = append(names, name)
+	}
+
+	for _, name := range parameters.Names() {
+		add(name)
+	}
+
+	return
+}
+
+// referenceDiagnostics returns a problem if a parameter does not exist.
+func (parameters Parameters) referenceDiagnostics(expr Expression, pos token.Position, serviceName string) (diagnostics Diagnostics) {
+	for _, name := range expr.ParameterReferences() {
+		if parameters[name] != nil {
+			continue
+		}
+
+		diagnostics = append(diagnostics, &Diagnostic{
+			Pos:     pos,
+			Service: serviceName,
+			Err:     unknownNameError("parameter", name, parameters.Names()),
+		})
+	}
+
+	return
+}
+
+// Imports returns the packages needed by the types of the parameters.
+func (parameters Parameters) Imports() map[string]string {
+	imports := map[string]string{}
+	for _, parameter := range parameters {
+		if parameter != nil && parameter.Type.PackageName() != "" {
+			imports[parameter.Type.PackageName()] = parameter.Type.LocalPackageName()
+		}
+	}
+
+	return imports
+}
+
+// parameterDiagnostics returns a problem for each parameter used by the service
+// that does not exist.
+func (service *Service) parameterDiagnostics(serviceName string, parameters Parameters) (diagnostics Diagnostics) {
+	check := func(expr Expression, keys ...string) {
+		diagnostics = append(diagnostics, parameters.referenceDiagnostics(expr,
+			service.Position(keys...), serviceName)...)
+	}
+
+	check(service.Returns, "returns")
+	for _, property := range service.SortedProperties() {
+		check(property.Value, "properties", property.Name)
+	}
+	check(service.Close, "close")
+	for _, hook := range service.OnInit {
+		check(hook, "on_init")
+	}
+
+	if len(Expression(service.Error).ParameterReferences()) > 0 {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Pos:     service.Position("error"),
+			Service: serviceName,
+			Err:     fmt.Errorf("error cannot use parameters"),
+		})
+	}
+
+	return
+}
+
+// Diagnostics returns every problem found with the parameters.
+func (parameters Parameters) Diagnostics() (diagnostics Diagnostics) {
+	fieldNames := map[string]string{}
+	for _, name := range parameters.Names() {
+		parameter := parameters[name]
+		if parameter == nil {
+			continue
+		}
+
+		var errs []error
+		if parameter.node != nil && parameter.node.Kind == yaml.MappingNode {
+			for i := 0; i < len(parameter.node.Content)-1; i += 2 {
+				if key := parameter.node.Content[i].Value; key != "type" && key != "value" {
+					errs = append(errs, fmt.Errorf("parameter %s has unknown key: %s", name, key))
+				}
+			}
+		}
+
+		if !regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`).MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid parameter name: %s", name))
+		} else if other, ok := fieldNames[parameterFieldName(name)]; ok {
+			errs = append(errs, fmt.Errorf("parameter %s has the same field name as %s: %s",
+				name, other, parameterFieldName(name)))
+		}
+		fieldNames[parameterFieldName(name)] = name
+
+		if parameter.Type == "" {
+			errs = append(errs, fmt.Errorf("parameter %s must have a type", name))
+		}
+
+		if len(parameter.Value.DependencyNames()) > 0 {
+			errs = append(errs, fmt.Errorf("parameter %s cannot reference services", name))
+		}
+
+		for _, v := range envReferenceRegexp.FindAllStringSubmatch(string(parameter.Value), -1) {
+			if _, err := parseEnvReference(v[1]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		for _, err := range errs {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos: parameter.Position(),
+				Err: err,
+			})
+		}
+
+		diagnostics = append(diagnostics,
+			parameters.referenceDiagnostics(parameter.Value, parameter.Position(), "")...)
+	}
+
+	for _, cycle := range parameters.Cycles() {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Pos: parameters[cycle[0]].Position(),
+			Err: fmt.Errorf("parameter cycle: %s", strings.Join(cycle, " -> ")),
+		})
+	}
+
+	return
+}
+
+// Cycles returns each of the cycles between parameters. The first and last name
+// of each cycle is the same parameter.
+func (parameters Parameters) Cycles() (cycles [][]string) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, ref := range parameters[name].Value.ParameterReferences() {
+			if parameters[ref] == nil {
+				continue
+			}
+
+			switch state[ref] {
+			case unvisited:
+				visit(ref)
+
+			case visiting:
+				for i, name := range path {
+					if name == ref {
+						cycle := append([]string{}, path[i:]...)
+						cycles = append(cycles, append(cycle, ref))
+					}
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, name := range parameters.Names() {
+		if parameters[name] != nil && state[name] == unvisited {
+			visit(name)
+		}
+	}
+
+	return
+}
+
+// locateParameters records where each parameter is defined in the YAML so that
+// problems can be reported with their location.
+func (file *File) locateParameters(path string, root *yaml.Node, paths map[*yaml.Node]string) {
+	parameters := mappingValue(root, "parameters")
+	if parameters == nil || parameters.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i < len(parameters.Content)-1; i += 2 {
+		name := parameters.Content[i]
+
+		// A parameter without a definition is reported as not having a type.
+		if parameter, ok := file.Parameters[name.Value]; ok && parameter == nil {
+			file.Parameters[name.Value] = &Parameter{}
+		}
+
+		if parameter := file.Parameters[name.Value]; parameter != nil {
+			parameter.path = path
+			if nodePath, ok := paths[name]; ok {
+				parameter.path = nodePath
+			}
+			parameter.name = name
+			parameter.node = parameters.Content[i+1]
+		}
+	}
+}
+
+// astParametersStruct creates the Parameters struct that contains a field for
+// each parameter.
+func (file *File) astParametersStruct() *ast.GenDecl {
+	var fields []*ast.Field
+	for _, name := range file.Parameters.Names() {
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{newIdent(parameterFieldName(name))},
+			Type:  newIdent(file.Parameters[name].Type.LocalEntityType()),
+		})
+	}
+
+	return &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: newIdent("Parameters"),
+				Type: &ast.StructType{
+					Fields: &ast.FieldList{List: fields},
+				},
+			},
+		},
+	}
+}
+
+// astInitParametersFuncs creates the methods that set each parameter to its
+// value. The parameters are set in order so that a parameter can use the ones
+// before it. The error of each parameter, such as an environment variable that
+// is not valid, is kept so that it is returned by the getters of the services
+// that use the parameter.
+func (file *File) astInitParametersFuncs() []ast.Decl {
+	var names []string
+	for _, name := range file.Parameters.Order() {
+		names = append(names, strconv.Quote(name))
+	}
+
+	initParameters := &ast.FuncDecl{
+		Name: newIdent("initParameters"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList(),
+			Results: newFieldList(),
+		},
+		Body: newBlock(
+			&ast.AssignStmt{
+				Tok: token.ASSIGN,
+				Lhs: []ast.Expr{newIdent("container.parameterErrors")},
+				Rhs: []ast.Expr{newIdent("map[string]error{}")},
+			},
+			&ast.RangeStmt{
+				Key:   newIdent("_"),
+				Value: newIdent("name"),
+				Tok:   token.DEFINE,
+				X:     newIdent("[]string{" + strings.Join(names, ", ") + "}"),
+				Body: newBlock(&ast.AssignStmt{
+					Tok: token.ASSIGN,
+					Lhs: []ast.Expr{newIdent("container.parameterErrors[name]")},
+					Rhs: []ast.Expr{newIdent("container.initParameter(name)")},
+				}),
+			},
+		),
+	}
+
+	var clauses []ast.Stmt
+	for _, name := range file.Parameters.Order() {
+		clauses = append(clauses, &ast.CaseClause{
+			List: []ast.Expr{newIdent(strconv.Quote(name))},
+			Body: file.astInitParameter(name),
+		})
+	}
+
+	initParameter := &ast.FuncDecl{
+		Name: newIdent("initParameter"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList("name string"),
+			Results: newFieldList("error"),
+		},
+		Body: newBlock(
+			&ast.SwitchStmt{
+				Tag:  newIdent("name"),
+				Body: newBlock(clauses...),
+			},
+			newReturn(newIdent("nil")),
+		),
+	}
+
+	return []ast.Decl{initParameters, initParameter}
+}
+
+// astInitParameter sets a parameter to its value. It returns the error of an
+// environment variable, or of a parameter that it references.
+func (file *File) astInitParameter(name string) (stmts []ast.Stmt) {
+	parameter := file.Parameters[name]
+
+	for _, ref := range parameter.Value.ParameterReferences() {
+		if file.Parameters.CanFail(ref) {
+			stmts = append(stmts, &ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("err")},
+					Rhs: []ast.Expr{newIdent(fmt.Sprintf("container.parameterErrors[%q]", ref))},
+				},
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent("err"))),
+			})
+		}
+	}
+
+	locals := map[string]string{}
+	used := map[string]bool{}
+	for _, ref := range parameter.Value.EnvReferences() {
+		// The same environment variable may be used in different forms.
+		local := envArgumentName(ref)
+		for i := 2; used[local]; i++ {
+			local = fmt.Sprintf("%s%d", envArgumentName(ref), i)
+		}
+		used[local] = true
+		locals[envLocalKey(ref)] = local
+
+		env, _ := parseEnvReference(ref)
+		call := newIdent(env.lookup())
+
+		if !env.CanFail() {
+			stmts = append(stmts, &ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent(local), newIdent("_")},
+				Rhs: []ast.Expr{call},
+			})
+			continue
+		}
+
+		file.addImport("fmt")
+		stmts = append(stmts,
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent(local), newIdent("err")},
+				Rhs: []ast.Expr{call},
+			},
+			&ast.IfStmt{
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent(
+					fmt.Sprintf(`fmt.Errorf("parameter %s: %%w", err)`, name)))),
+			},
+		)
+	}
+
+	return append(stmts, &ast.AssignStmt{
+		Tok: token.ASSIGN,
+		Lhs: []ast.Expr{newIdent("container.Parameters." + parameterFieldName(name))},
+		Rhs: []ast.Expr{newIdent(parameter.Value.performSubstitutions(file,
+			file.Services, false, locals))},
+	})
+}
+
+// astCheckParameters returns the error of each parameter used by the service
+// that can fail, before the service is created.
+func (service *Service) astCheckParameters(file *File, serviceName string) (stmts []ast.Stmt) {
+	for _, name := range service.parameterReferences() {
+		if !service.parameters.CanFail(name) {
+			continue
+		}
+
+		stmts = append(stmts, &ast.IfStmt{
+			Init: &ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("err")},
+				Rhs: []ast.Expr{newIdent(fmt.Sprintf("container.parameterErrors[%q]", name))},
+			},
+			Cond: newIdent("err != nil"),
+			Body: newBlock(service.astReturnError(file, serviceName)),
+		})
+	}
+
+	return
+}
+
+// parameterForNode returns the parameter name if the node sets the parameter in
+// initParameters.
+func (file *File) parameterForNode(node ast.Node) string {
+	assign, ok := node.(*ast.AssignStmt)
+	if !ok {
+		return ""
+	}
+
+	lhs, ok := assign.Lhs[0].(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+
+	if x, ok := lhs.X.(*ast.SelectorExpr); !ok || x.Sel.Name != "Parameters" {
+		return ""
+	}
+
+	for _, name := range file.Parameters.Names() {
+		if parameterFieldName(name) == lhs.Sel.Name {
+			return name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParameterFieldName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"timeout":     "Timeout",
+		"base_url":    "BaseURL",
+		"api_url":     "APIURL",
+		"api_timeout": "APITimeout",
+		"user_id":     "UserID",
+		"maxConns":    "MaxConns",
+		"feature_x":   "FeatureX",
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, parameterFieldName(name))
+		})
+	}
+}
+
+func TestParameters_Order(t *testing.T) {
+	parameters := Parameters{
+		"a": {Type: "string", Value: `%{c} + %{b}`},
+		"b": {Type: "string", Value: `%{c}`},
+		"c": {Type: "string", Value: `"c"`},
+		"d": {Type: "int", Value: `1`},
+	}
+
+	assert.Equal(t, []string{"c", "b", "a", "d"}, parameters.Order())
+}
+
+func TestService_ParameterReferences(t *testing.T) {
+	service := &Service{
+		Returns:    "NewA(%{timeout}, %{base_url})",
+		Properties: map[string]Expression{"URL": "%{base_url}"},
+		OnInit:     []Expression{"@{A}.SetRetries(%{retries})"},
+	}
+
+	assert.Equal(t, []string{"base_url", "retries", "timeout"},
+		service.parameterReferences())
+}
+
+func TestFile_ValidateParameters(t *testing.T) {
+	file := parseYAML(t, `parameters:
+  base_url:
+    type: string
+    value: '"http://localhost"'
+  api_url:
+    type: string
+    value: '%{base_url} + %{api_path}'
+  a:
+    type: int
+    value: '%{b}'
+  b:
+    type: int
+    value: '%{a}'
+  missing_type:
+    value: '1'
+  service:
+    type: string
+    value: '@{A}.URL'
+  timeout:
+    type: time.Duration
+    default: 5s
+  bad-name:
+    type: int
+  port:
+    type: int
+    value: ${PORT:int=http}
+services:
+  A:
+    type: '*A'
+    returns: NewA(%{base_ur})
+    error: panic(%{base_url})
+`)
+
+	err := file.Validate()
+	if assert.IsType(t, Diagnostics{}, err) {
+		var actual []string
+		for _, diagnostic := range err.(Diagnostics) {
+			actual = append(actual, diagnostic.Error())
+		}
+
+		assert.Equal(t, []string{
+			file.path + ":5:3: parameter does not exist: api_path",
+			file.path + ":8:3: parameter cycle: a -> b -> a",
+			file.path + ":14:3: parameter missing_type must have a type",
+			file.path + ":16:3: parameter service cannot reference services",
+			file.path + ":19:3: parameter timeout has unknown key: default",
+			file.path + ":22:3: invalid parameter name: bad-name",
+			file.path + ":24:3: invalid default for environment variable PORT: strconv.Atoi: parsing \"http\": invalid syntax",
+			file.path + ":30:5: A: parameter does not exist: base_ur (did you mean base_url?)",
+			file.path + ":31:5: A: error cannot use parameters",
+		}, actual)
+	}
+}
+
+func TestParameters_CanFail(t *testing.T) {
+	parameters := Parameters{
+		"host":    {Type: "string", Value: "${HOST=localhost}"},
+		"port":    {Type: "int", Value: "${PORT:int=80}"},
+		"address": {Type: "string", Value: `%{host} + ":" + fmt.Sprint(%{port})`},
+		"url":     {Type: "string", Value: `"http://" + %{host}`},
+		"a":       {Type: "string", Value: "%{b}"},
+		"b":       {Type: "string", Value: "%{a}"},
+	}
+
+	for name, expected := range map[string]bool{
+		"host":    false,
+		"port":    true,
+		"address": true,
+		"url":     false,
+		"a":       false,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, parameters.CanFail(name))
+		})
+	}
+}
+
+func TestGenerateContainer_Parameters(t *testing.T) {
+	source := generateSource(t, `parameters:
+  port:
+    type: int
+    value: ${PORT:int} + ${PORT:int=80}
+  next_port:
+    type: int
+    value: '%{port} + 1'
+services:
+  A:
+    type: '*A'
+    returns: NewA(%{next_port})
+  P:
+    type: '*P'
+    scope: prototype
+    returns: NewP(%{port})
+`)
+
+	// Each form of the environment variable has its own local.
+	assert.Contains(t, source, `envPORT, err := container.envInt("PORT", "", false)`+"\n")
+	assert.Contains(t, source, `envPORT2, err := container.envInt("PORT", "80", false)`+"\n")
+	assert.Contains(t, source, "container.Parameters.Port = envPORT + envPORT2\n")
+
+	// The error is returned by the getters instead of NewContainer.
+	assert.Contains(t, source, "\tcontainer.initParameters()\n\treturn container\n")
+	assert.Contains(t, source, `if err := container.parameterErrors["port"]; err != nil {`+
+		"\n\t\t\treturn err\n")
+	assert.Contains(t, source, `if err := container.parameterErrors["next_port"]; err != nil {`+
+		"\n\t\t\treturn nil, fmt.Errorf(\"A: %w\", err)\n")
+	assert.Contains(t, source, `if err := container.parameterErrors["port"]; err != nil {`+
+		"\n\t\treturn nil, fmt.Errorf(\"P: %w\", err)\n")
+}
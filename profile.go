@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverlayPath returns the path of the overlay for a profile, such as
+// "dingo.dev.yml" for the profile "dev" of "dingo.yml".
+func OverlayPath(configPath, profile string) string {
+	ext := filepath.Ext(configPath)
+
+	return strings.TrimSuffix(configPath, ext) + "." + profile + ext
+}
+
+// Profiles returns the name of each profile that has an overlay next to the
+// config file.
+func Profiles(configPath string) (profiles []string, err error) {
+	ext := filepath.Ext(configPath)
+	prefix := strings.TrimSuffix(configPath, ext) + "."
+
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+		profile := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		if profile != "" && !strings.Contains(profile, ".") {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	sort.Strings(profiles)
+
+	return
+}
+
+// mergeNodes merges overlay onto base and returns the result. Mappings are
+// merged key by key, a key with a null value is removed, and any other value in
+// overlay replaces the value in base.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if overlay.Kind == 0 {
+		return base
+	}
+
+	if base.Kind == 0 {
+		return overlay
+	}
+
+	if base.Kind == yaml.DocumentNode && overlay.Kind == yaml.DocumentNode &&
+		len(base.Content) > 0 && len(overlay.Content) > 0 {
+		base.Content[0] = mergeNodes(base.Content[0], overlay.Content[0])
+
+		return base
+	}
+
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+
+	for i := 0; i < len(overlay.Content)-1; i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+
+		j := 0
+		for ; j < len(base.Content)-1; j += 2 {
+			if base.Content[j].Value == key.Value {
+				break
+			}
+		}
+
+		switch {
+		case value.Kind == yaml.ScalarNode && value.ShortTag() == "!!null":
+			if j < len(base.Content)-1 {
+				base.Content = append(base.Content[:j], base.Content[j+2:]...)
+			}
+
+		case j >= len(base.Content)-1:
+			base.Content = append(base.Content, key, value)
+
+		case base.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(base.Content[j+1], value)
+
+		default:
+			// The key is also replaced so that it is located in the overlay.
+			base.Content[j], base.Content[j+1] = key, value
+		}
+	}
+
+	return base
+}
+
+// locateNodes records that node, and every node inside it, is in the file at
+// path.
+func locateNodes(node *yaml.Node, path string, paths map[*yaml.Node]string) {
+	paths[node] = path
+	for _, child := range node.Content {
+		locateNodes(child, path, paths)
+	}
+}
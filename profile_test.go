@@ -0,0 +1,211 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "dingo")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	for path, contents := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	return dir
+}
+
+func TestOverlayPath(t *testing.T) {
+	for configPath, expected := range map[string]string{
+		"dingo.yml":          "dingo.dev.yml",
+		"foo/wiring.yaml":    "foo/wiring.dev.yaml",
+		"foo.bar/dingo.yaml": "foo.bar/dingo.dev.yaml",
+	} {
+		t.Run(configPath, func(t *testing.T) {
+			assert.Equal(t, expected, OverlayPath(configPath, "dev"))
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml":        "",
+		"dingo.test.yml":   "",
+		"dingo.dev.yml":    "",
+		"dingo.dev.bk.yml": "",
+		"dingo.go":         "",
+		"wiring.prod.yml":  "",
+	})
+
+	profiles, err := Profiles(filepath.Join(dir, "dingo.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "test"}, profiles)
+}
+
+func TestParseYAMLFile_Overlay(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `services:
+  A:
+    type: '*A'
+    returns: NewA()
+    properties:
+      B: '@{B}'
+      C: '@{C}'
+  B:
+    type: '*B'
+  C:
+    type: '*C'
+`,
+		"dingo.dev.yml": `services:
+  A:
+    returns: NewDevA()
+    properties:
+      C: null
+      D: '"d"'
+  B: ~
+  E:
+    type: '*E'
+`,
+	})
+
+	basePath := filepath.Join(dir, "dingo.yml")
+	overlayPath := filepath.Join(dir, "dingo.dev.yml")
+	file, err := ParseYAMLFile(basePath, overlayPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"A", "C", "E"}, file.Services.ServiceNames())
+	assert.Equal(t, Expression("NewDevA()"), file.Services["A"].Returns)
+	assert.Equal(t, Type("*A"), file.Services["A"].Type)
+	assert.Equal(t, map[string]Expression{
+		"B": "@{B}",
+		"D": `"d"`,
+	}, file.Services["A"].Properties)
+
+	for testName, test := range map[string]struct {
+		service  string
+		keys     []string
+		expected string
+	}{
+		"Type":          {"A", []string{"type"}, basePath + ":3:5"},
+		"Returns":       {"A", []string{"returns"}, overlayPath + ":3:5"},
+		"BaseProperty":  {"A", []string{"properties", "B"}, basePath + ":6:7"},
+		"AddedProperty": {"A", []string{"properties", "D"}, overlayPath + ":6:7"},
+		"AddedService":  {"E", nil, overlayPath + ":8:3"},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			pos := file.Services[test.service].Position(test.keys...)
+			assert.Equal(t, test.expected, pos.String())
+		})
+	}
+}
+
+func TestGenerate_Profile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dingo.yml": `package: foo
+services:
+  A:
+    type: int
+    returns: 1
+`,
+		"dingo.dev.yml": `services:
+  A:
+    returns: 2
+`,
+		"dingo.test.yml": "",
+	})
+
+	for testName, test := range map[string]struct {
+		opts     Options
+		expected []string
+	}{
+		"Base": {
+			Options{},
+			[]string{"service := 1\n"},
+		},
+		"Dev": {
+			Options{Profile: "dev"},
+			[]string{"service := 2\n"},
+		},
+		"MissingOverlay": {
+			Options{Profile: "prod"},
+			[]string{"service := 1\n"},
+		},
+		"BaseTags": {
+			Options{ProfileTags: true},
+			[]string{"//go:build !dev && !test\n", "service := 1\n"},
+		},
+		"DevTags": {
+			Options{Profile: "dev", ProfileTags: true},
+			[]string{"//go:build dev && !test\n", "service := 2\n"},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			opts := test.opts
+			opts.Dir, opts.Config, opts.Out = dir, "dingo.yml", testName+".go"
+			require.NoError(t, generate(opts))
+
+			source, err := ioutil.ReadFile(opts.OutPath())
+			require.NoError(t, err)
+			for _, expected := range test.expected {
+				assert.Contains(t, string(source), expected)
+			}
+		})
+	}
+}
+
+func TestGenerate_ProfileTags(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod": "module foo\n\ngo 1.22\n",
+		"dingo.yml": `package: foo
+services:
+  A:
+    type: int
+    returns: 1
+`,
+		"dingo.dev.yml": `services:
+  A:
+    returns: 2
+`,
+	})
+
+	for profile, out := range map[string]string{"": "dingo.go", "dev": "dingo_dev.go"} {
+		require.NoError(t, generate(Options{Dir: dir, Config: "dingo.yml",
+			Out: out, Profile: profile, ProfileTags: true, TypeCheck: true}))
+	}
+
+	// Only one of the files is built for each set of tags.
+	for _, tags := range []string{"", "dev", "prod", "dev,prod"} {
+		t.Run("Tags="+tags, func(t *testing.T) {
+			pkgs, err := packages.Load(&packages.Config{
+				Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports |
+					packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax |
+					packages.NeedTypesInfo,
+				Dir:        dir,
+				BuildFlags: []string{"-tags=" + tags},
+			}, ".")
+			require.NoError(t, err)
+			require.Len(t, pkgs, 1)
+			assert.Empty(t, pkgs[0].Errors)
+			assert.Len(t, pkgs[0].Syntax, 1)
+		})
+	}
+
+	t.Run("MissingOverlay", func(t *testing.T) {
+		err := generate(Options{Dir: dir, Config: "dingo.yml", Out: "dingo_prod.go",
+			Profile: "prod", ProfileTags: true})
+		assert.EqualError(t, err, filepath.Join(dir, "dingo.yml")+
+			": profile prod must have an overlay to be a build tag: "+
+			filepath.Join(dir, "dingo.prod.yml"))
+	})
+}
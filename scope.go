@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// CapturedRequestServices returns the request scoped services that would be
+// kept for the life of the container by a container scoped service. Prototypes
+// that are called while creating the service are followed because the service
+// keeps what they return.
+func (services Services) CapturedRequestServices(serviceName string) (captured []string) {
+	seen := map[string]bool{serviceName: true}
+
+	var visit func(serviceName string)
+	visit = func(serviceName string) {
+		for _, dep := range services.Dependencies(serviceName) {
+			if seen[dep] {
+				continue
+			}
+
+			seen[dep] = true
+			switch services[dep].Scope {
+			case ScopeRequest:
+				captured = append(captured, dep)
+
+			case ScopePrototype:
+				visit(dep)
+
+			case ScopeNotSet:
+				// An alias, or a service with when, has the scope of the
+				// services it refers to.
+				if services[dep].isReference() {
+					visit(dep)
+				}
+			}
+		}
+	}
+
+	visit(serviceName)
+
+	return
+}
+
+// scopeDiagnostics returns a problem for each request scoped service that a
+// container scoped service depends on.
+func (services Services) scopeDiagnostics(serviceName string) (diagnostics Diagnostics) {
+	service := services[serviceName]
+	if !isContainerScope(service.Scope) || service.isReference() {
+		return nil
+	}
+
+	for _, dep := range services.CapturedRequestServices(serviceName) {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Pos:     service.Position(),
+			Service: serviceName,
+			Err: fmt.Errorf("container scoped service cannot depend on request scoped service: %s",
+				dep),
+		})
+	}
+
+	return
+}
+
+// astParentGetter returns the container scoped service from the parent, if the
+// container was created with NewScope, so that it is shared by all scopes. A
+// service that has been set on the child itself is used instead.
+func (service *Service) astParentGetter(services Services, serviceName string) ast.Stmt {
+	ref := serviceName
+	if len(service.Arguments) > 0 {
+		ref += "(" + strings.Join(service.Arguments.Names(), ", ") + ")"
+	}
+
+	getter := services.getterCall(ref, services.UsesContext(serviceName))
+	if services.Decorated(serviceName) != serviceName {
+		// The decorators are applied by the child.
+		getter = services.innerCall(services.Decorators(serviceName)[0], true)
+	}
+
+	return &ast.IfStmt{
+		Cond: newIdent("container.parent != nil && container." + serviceName + " == nil"),
+		Body: newBlock(newReturn(newIdent(
+			strings.Replace(getter, "container.", "container.parent.", 1)))),
+	}
+}
+
+// astNewScopeFunc creates Container.NewScope. The child container shares the
+// container scoped services with its parent and creates its own request scoped
+// services. Prototype functions are copied so that any that were replaced on
+// the parent are also used by the child. The parameters are copied from the
+// parent rather than set again.
+func (file *File) astNewScopeFunc() *ast.FuncDecl {
+	stmts := []ast.Stmt{
+		&ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent("scope")},
+			Rhs: []ast.Expr{newIdent(file.newContainerFuncName() + "()")},
+		},
+		&ast.AssignStmt{
+			Tok: token.ASSIGN,
+			Lhs: []ast.Expr{newIdent("scope.parent")},
+			Rhs: []ast.Expr{newIdent("container")},
+		},
+	}
+
+	// The parameters may have been changed on the parent.
+	if len(file.Parameters) > 0 {
+		stmts = append(stmts, &ast.AssignStmt{
+			Tok: token.ASSIGN,
+			Lhs: []ast.Expr{newIdent("scope.Parameters"), newIdent("scope.parameterErrors")},
+			Rhs: []ast.Expr{newIdent("container.Parameters"), newIdent("container.parameterErrors")},
+		})
+	}
+
+	for _, serviceName := range file.Services.ServicesWithScope(ScopePrototype).ServiceNames() {
+		stmts = append(stmts, &ast.AssignStmt{
+			Tok: token.ASSIGN,
+			Lhs: []ast.Expr{newIdent("scope." + serviceName)},
+			Rhs: []ast.Expr{newIdent("container." + serviceName)},
+		})
+	}
+
+	stmts = append(stmts, newReturn(newIdent("scope")))
+
+	return &ast.FuncDecl{
+		Name: newIdent("NewScope"),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList(),
+			Results: newFieldList("*Container"),
+		},
+		Body: newBlock(stmts...),
+	}
+}
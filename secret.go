@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+// fileReferencePrefix starts a reference to a file, such as
+// "${file:/run/secrets/db_password}".
+const fileReferencePrefix = "file:"
+
+// readFileFuncName is the Container method that reads a file.
+const readFileFuncName = "readFile"
+
+// parseFileReference parses a reference to a file, without the prefix. The
+// forms are "path" and "path=fallback". A file without a fallback is required,
+// the getter returns an error if it cannot be read.
+func parseFileReference(ref string) (*EnvReference, error) {
+	env := &EnvReference{File: true}
+
+	if i := strings.Index(ref, "="); i >= 0 {
+		ref, env.Default, env.HasDefault = ref[:i], ref[i+1:], true
+	}
+
+	if ref == "" {
+		return nil, errors.New("file must have a path")
+	}
+
+	env.Name = ref
+	env.Required = !env.HasDefault
+
+	return env, nil
+}
+
+// fileArgumentName is the name of the local variable, or prototype function
+// argument, for a file, such as "file_run_secrets_db_password_57e29e5c". It
+// ends with a hash of the path so that paths that only differ by punctuation,
+// such as "/a-b" and "/a_b", have different names.
+func fileArgumentName(path string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(path))
+
+	return fmt.Sprintf("file%s_%08x",
+		regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString("_"+path, "_"),
+		hash.Sum32())
+}
+
+// astReadFileFunc creates the Container method that returns the contents of a
+// file without leading and trailing whitespace. The default value is used if
+// the file does not exist and it is not required. Any other error, such as
+// permission denied, is always returned.
+func (file *File) astReadFileFunc() *ast.FuncDecl {
+	file.addImport("os")
+	file.addImport("strings")
+
+	return &ast.FuncDecl{
+		Name: newIdent(readFileFuncName),
+		Recv: newReceiver(),
+		Type: &ast.FuncType{
+			Params:  newFieldList("path, defaultValue string", "required bool"),
+			Results: newFieldList("string", "error"),
+		},
+		Body: newBlock(
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("data"), newIdent("err")},
+				Rhs: []ast.Expr{newIdent("os.ReadFile(path)")},
+			},
+			&ast.IfStmt{
+				Cond: newIdent("os.IsNotExist(err) && !required"),
+				Body: newBlock(newReturn(newIdent("defaultValue"), newIdent("nil"))),
+			},
+			&ast.IfStmt{
+				Cond: newIdent("err != nil"),
+				Body: newBlock(newReturn(newIdent(`""`), newIdent("err"))),
+			},
+			newReturn(newIdent("strings.TrimSpace(string(data))"), newIdent("nil")),
+		),
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"golang.org/x/tools/go/ast/astutil"
+	"gopkg.in/yaml.v3"
+	"reflect"
 	"sort"
 	"strings"
 )
@@ -12,33 +15,177 @@ const (
 	ScopeNotSet    = ""
 	ScopePrototype = "prototype"
 	ScopeContainer = "container"
+	ScopeRequest   = "request"
 )
 
 type Service struct {
+	Alias      string
 	Arguments  Arguments
+	Close      Expression
+	Decorates  string
+	Deprecated string
+	Eager      bool
 	Error      string
 	Import     []string
 	Interface  Type
+	OnInit     []Expression `yaml:"on_init"`
+	Priority   int
 	Properties map[string]Expression
 	Returns    Expression
 	Scope      string
+	Tags       []string
 	Type       Type
+	When       []*Condition
+
+	// ReturnsError means that returns provides the service and an error. The
+	// getter will return the error, rather than handling it with Error.
+	ReturnsError bool `yaml:"returns_error"`
+
+	// ThreadSafe overrides the thread_safe option of the file for this
+	// service.
+	ThreadSafe *bool `yaml:"thread_safe"`
+
+	// path, name and node are the location of the service in the YAML file.
+	// They are used to report problems. paths contains the file of each node,
+	// because a service can be changed by an overlay.
+	path  string
+	paths map[*yaml.Node]string
+	name  *yaml.Node
+	node  *yaml.Node
+
+	// parameters are those of the file. The prototype function receives the
+	// parameters used by the service as arguments of their type.
+	parameters Parameters
+}
+
+// serviceKeys are all of the valid YAML keys for a service.
+var serviceKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	ty := reflect.TypeOf(Service{})
+	for i := 0; i < ty.NumField(); i++ {
+		if key := serviceKey(ty.Field(i)); key != "" {
+			keys[key] = true
+		}
+	}
+
+	return keys
+}()
+
+// serviceKey returns the YAML key for a field of Service, or an empty string if
+// the field is not read from the YAML.
+func serviceKey(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if key == "" {
+		key = strings.ToLower(field.Name)
+	}
+
+	return key
+}
+
+// nonZeroKeysExcept returns the YAML keys that have a value, other than the
+// allowed keys. The keys are in the order of the fields of Service.
+func (service *Service) nonZeroKeysExcept(allowed ...string) (keys []string) {
+	isAllowed := map[string]bool{"": true}
+	for _, key := range allowed {
+		isAllowed[key] = true
+	}
+
+	value := reflect.ValueOf(*service)
+	for i := 0; i < value.NumField(); i++ {
+		key := serviceKey(value.Type().Field(i))
+		if !isAllowed[key] && !value.Field(i).IsZero() {
+			keys = append(keys, key)
+		}
+	}
+
+	return
+}
+
+// Position returns the location of a key in the YAML file. Nested keys, such
+// as a property, can be located by providing each key in the path. If a key
+// does not exist, the location of its closest parent is returned.
+func (service *Service) Position(keys ...string) token.Position {
+	node, value := service.name, service.node
+	for _, key := range keys {
+		if value == nil || value.Kind != yaml.MappingNode {
+			break
+		}
+
+		found := false
+		for i := 0; i < len(value.Content)-1; i += 2 {
+			if value.Content[i].Value == key {
+				node, value = value.Content[i], value.Content[i+1]
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	path := service.path
+	if nodePath, ok := service.paths[node]; ok {
+		path = nodePath
+	}
+
+	if node == nil {
+		return token.Position{Filename: path}
+	}
+
+	return token.Position{
+		Filename: path,
+		Line:     node.Line,
+		Column:   node.Column,
+	}
 }
 
 func (service *Service) ContainerFieldType(services Services) ast.Expr {
+	if target := services[service.Alias]; target != nil {
+		return target.ContainerFieldType(services)
+	}
+
 	scope := service.Scope
 	if scope == ScopeNotSet {
 		scope = ScopeContainer
 	}
 
-	if scope == ScopeContainer && len(service.Arguments) == 0 {
+	if (scope == ScopeContainer || scope == ScopeRequest) &&
+		len(service.Arguments) == 0 {
 		return newIdent(service.InterfaceOrLocalEntityPointerType())
 	}
 
 	return service.astFunctionPrototype(services)
 }
 
+// IsThreadSafe returns true if the service is created once for the container,
+// or once for the scope, and access to it must be synchronised.
+func (service *Service) IsThreadSafe(file *File) bool {
+	if service.Scope == ScopePrototype {
+		return false
+	}
+
+	if len(service.Arguments) > 0 {
+		return false
+	}
+
+	if service.ThreadSafe != nil {
+		return *service.ThreadSafe
+	}
+
+	return file.ThreadSafe
+}
+
 func (service *Service) InterfaceOrLocalEntityType(services Services, recurse bool) string {
+	if target := services[service.Alias]; target != nil {
+		return target.InterfaceOrLocalEntityType(services, recurse)
+	}
+
 	localEntityType := service.Type.LocalEntityType()
 	if service.Interface != "" {
 		localEntityType = service.Interface.LocalEntityType()
@@ -46,12 +193,30 @@ func (service *Service) InterfaceOrLocalEntityType(services Services, recurse bo
 
 	if len(service.Arguments) > 0 && recurse {
 		var args []string
+		if service.usesContext() {
+			args = append(args, ContextReference+" context.Context")
+		}
 
-		for _, dep := range service.Returns.Dependencies() {
+		for _, dep := range services.expressionDependencies(service.Returns) {
 			ty := services[dep].InterfaceOrLocalEntityType(services, false)
 			args = append(args, fmt.Sprintf("%s %s", dep, ty))
 		}
 
+		for _, ref := range service.Returns.ArgumentReferences() {
+			args = append(args, fmt.Sprintf("%s %s", argumentName(ref),
+				services.argumentType(ref)))
+		}
+
+		for _, ref := range service.envReferences() {
+			args = append(args, fmt.Sprintf("%s %s", envArgumentName(ref),
+				envGoType(ref)))
+		}
+
+		for _, name := range service.parameterReferences() {
+			args = append(args, fmt.Sprintf("%s %s", parameterArgumentName(name),
+				service.parameters[name].Type.LocalEntityType()))
+		}
+
 		args = append(args, service.Arguments.GoArguments()...)
 
 		return fmt.Sprintf("func(%v) %s", strings.Join(args, ", "),
@@ -105,23 +270,219 @@ func (service *Service) SortedProperties() (sortedProperties []*Property) {
 	return
 }
 
+// Expressions returns all of the expressions that may reference other
+// services.
+func (service *Service) Expressions() []Expression {
+	expressions := []Expression{service.Returns}
+
+	for _, property := range service.SortedProperties() {
+		expressions = append(expressions, property.Value)
+	}
+
+	return append(expressions, Expression(service.Error))
+}
+
 func (service *Service) ValidateScope() error {
 	switch service.Scope {
-	case ScopeNotSet, ScopePrototype, ScopeContainer:
+	case ScopeNotSet, ScopePrototype, ScopeContainer, ScopeRequest:
 		return nil
 	}
 
 	return fmt.Errorf("invalid scope: %s", service.Scope)
 }
 
+func (service *Service) ValidateError() error {
+	if service.Error != "" && service.Returns == "" {
+		return fmt.Errorf("error cannot be used without returns")
+	}
+
+	if service.Error != "" && service.ReturnsError {
+		return fmt.Errorf("error cannot be used with returns_error")
+	}
+
+	return nil
+}
+
+func (service *Service) ValidateClose() error {
+	if service.Close != "" && service.Scope == ScopePrototype {
+		return fmt.Errorf("close cannot be used with prototype scope")
+	}
+
+	if service.Close.UsesContext() {
+		return fmt.Errorf("close cannot use @{%s}", ContextReference)
+	}
+
+	if service.Close.UsesInner() {
+		return fmt.Errorf("close cannot use @{%s}", InnerReference)
+	}
+
+	if len(service.Close.EnvReferences()) > 0 {
+		return fmt.Errorf("close can only use plain environment variables")
+	}
+
+	return nil
+}
+
+func (service *Service) ValidateEager() error {
+	if service.Eager && service.Scope == ScopePrototype {
+		return fmt.Errorf("eager cannot be used with prototype scope")
+	}
+
+	if service.Eager && service.Scope == ScopeRequest {
+		return fmt.Errorf("eager cannot be used with request scope")
+	}
+
+	if service.Eager && len(service.Arguments) > 0 {
+		return fmt.Errorf("eager cannot be used with arguments")
+	}
+
+	return nil
+}
+
+func (service *Service) ValidateDecorates() error {
+	if service.Decorates != "" && service.Scope == ScopePrototype {
+		return fmt.Errorf("decorates cannot be used with prototype scope")
+	}
+
+	if service.Decorates != "" && len(service.Arguments) > 0 {
+		return fmt.Errorf("decorates cannot be used with arguments")
+	}
+
+	if service.Decorates == "" && service.usesInner() {
+		return fmt.Errorf("@{%s} can only be used with decorates", InnerReference)
+	}
+
+	return nil
+}
+
+func (service *Service) ValidateTags() error {
+	if len(service.Tags) > 0 && len(service.Arguments) > 0 {
+		return fmt.Errorf("tags cannot be used with arguments")
+	}
+
+	return nil
+}
+
+func (service *Service) ValidateReturnsError() error {
+	if service.ReturnsError && service.Returns == "" {
+		return fmt.Errorf("returns_error cannot be used without returns")
+	}
+
+	return nil
+}
+
+type serviceValidation struct {
+	// key is used for the location of the problem.
+	key      string
+	validate func() error
+}
+
+func (service *Service) validations() []serviceValidation {
+	return []serviceValidation{
+		{"scope", service.ValidateScope},
+		{"error", service.ValidateError},
+		{"returns_error", service.ValidateReturnsError},
+		{"close", service.ValidateClose},
+		{"eager", service.ValidateEager},
+		{"tags", service.ValidateTags},
+		{"decorates", service.ValidateDecorates},
+		{"alias", service.ValidateAlias},
+		{"when", service.ValidateWhen},
+	}
+}
+
+// Validate returns the first problem found with the service.
 func (service *Service) Validate() error {
-	if err := service.ValidateScope(); err != nil {
-		return err
+	for _, validation := range service.validations() {
+		if err := validation.validate(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// referenceDiagnostics returns a problem for each reference to a service that
+// does not exist.
+func (service *Service) referenceDiagnostics(serviceName string, services Services) (diagnostics Diagnostics) {
+	check := func(expr Expression, keys ...string) {
+		diagnostics = append(diagnostics,
+			service.envDiagnostics(serviceName, expr, keys...)...)
+
+		for _, ref := range expr.TagReferences() {
+			tag, _, _ := parseTagReference(ref)
+			if _, err := services.TagType(tag); err != nil {
+				diagnostics = append(diagnostics, &Diagnostic{
+					Pos:     service.Position(keys...),
+					Service: serviceName,
+					Err:     err,
+				})
+			}
+		}
+
+		for _, ref := range expr.LazyReferences() {
+			if err := services.lazyError(ref); err != nil {
+				diagnostics = append(diagnostics, &Diagnostic{
+					Pos:     service.Position(keys...),
+					Service: serviceName,
+					Err:     err,
+				})
+			}
+		}
+
+		for _, dep := range expr.DependencyNames() {
+			if _, ok := services[dep]; ok {
+				continue
+			}
+
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     service.Position(keys...),
+				Service: serviceName,
+				Err:     unknownNameError("service", dep, services.ServiceNames()),
+			})
+		}
+	}
+
+	check(service.Returns, "returns")
+	for _, property := range service.SortedProperties() {
+		check(property.Value, "properties", property.Name)
+	}
+	check(Expression(service.Error), "error")
+	check(service.Close, "close")
+	for _, hook := range service.OnInit {
+		check(hook, "on_init")
+	}
+
+	return
+}
+
+// Diagnostics returns every problem found with the service.
+func (service *Service) Diagnostics(serviceName string) (diagnostics Diagnostics) {
+	if service.node != nil && service.node.Kind == yaml.MappingNode {
+		for i := 0; i < len(service.node.Content)-1; i += 2 {
+			if key := service.node.Content[i].Value; !serviceKeys[key] {
+				diagnostics = append(diagnostics, &Diagnostic{
+					Pos:     service.Position(key),
+					Service: serviceName,
+					Err:     fmt.Errorf("unknown key: %s", key),
+				})
+			}
+		}
+	}
+
+	for _, validation := range service.validations() {
+		if err := validation.validate(); err != nil {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     service.Position(validation.key),
+				Service: serviceName,
+				Err:     err,
+			})
+		}
+	}
+
+	return
+}
+
 func (service *Service) astArguments() *ast.FieldList {
 	funcParams := &ast.FieldList{
 		List: []*ast.Field{},
@@ -143,12 +504,31 @@ func (service *Service) astDependencyArguments(services Services) *ast.FieldList
 		List: []*ast.Field{},
 	}
 
-	for _, dep := range service.Returns.DependencyNames() {
+	for _, dep := range services.expressionDependencyNames(service.Returns) {
 		funcParams.List = append(funcParams.List, &ast.Field{
 			Type: newIdent(dep + " " + services[dep].InterfaceOrLocalEntityType(services, false)),
 		})
 	}
 
+	for _, ref := range service.Returns.ArgumentReferences() {
+		funcParams.List = append(funcParams.List, &ast.Field{
+			Type: newIdent(argumentName(ref) + " " + services.argumentType(ref)),
+		})
+	}
+
+	for _, ref := range service.envReferences() {
+		funcParams.List = append(funcParams.List, &ast.Field{
+			Type: newIdent(envArgumentName(ref) + " " + envGoType(ref)),
+		})
+	}
+
+	for _, name := range service.parameterReferences() {
+		funcParams.List = append(funcParams.List, &ast.Field{
+			Type: newIdent(parameterArgumentName(name) + " " +
+				service.parameters[name].Type.LocalEntityType()),
+		})
+	}
+
 	return funcParams
 }
 
@@ -156,79 +536,273 @@ func (service *Service) astAllArguments(services Services) *ast.FieldList {
 	deps := service.astDependencyArguments(services)
 	args := service.astArguments()
 
+	if service.usesContext() {
+		deps.List = append(newFieldList(ContextReference+" context.Context").List,
+			deps.List...)
+	}
+
 	return &ast.FieldList{
 		List: append(deps.List, args.List...),
 	}
 }
 
 func (service *Service) astFunctionPrototype(services Services) *ast.FuncType {
+	var funcType *ast.FuncType
+
 	ty := Type(service.InterfaceOrLocalEntityType(services, true))
 	if ty.IsFunction() {
 		args, returns := ty.parseFunctionType()
 
-		return &ast.FuncType{
+		funcType = &ast.FuncType{
 			Params:  newFieldList(args),
 			Results: newFieldList(returns...),
 		}
+	} else {
+		funcType = &ast.FuncType{
+			Params:  service.astAllArguments(services),
+			Results: newFieldList(string(ty)),
+		}
+	}
+
+	if service.ReturnsError {
+		funcType.Results.List = append(funcType.Results.List,
+			newFieldList("error").List...)
+	}
+
+	return funcType
+}
+
+// ZeroValue is the Go expression for the zero value of the service type. It is
+// returned with an error.
+func (service *Service) ZeroValue() string {
+	if service.Interface != "" || service.Type.IsPointer() {
+		return "nil"
+	}
+
+	return "*new(" + service.Type.LocalEntityType() + ")"
+}
+
+// astReturnError returns the error, wrapped with the service name so that
+// errors include the chain of services that were being created.
+func (service *Service) astReturnError(file *File, serviceName string) ast.Stmt {
+	astutil.AddImport(file.fset, file.file, "fmt")
+
+	return newReturn(
+		newIdent(service.ZeroValue()),
+		newIdent(fmt.Sprintf("fmt.Errorf(\"%s: %%w\", err)", serviceName)),
+	)
+}
+
+// astResolveDependencies creates a local variable for each dependency that
+// returns an error, so that the error can be returned before the service is
+// created. The returned locals map each reference to its variable.
+func (service *Service) astResolveDependencies(file *File, services Services, serviceName string, expressions []Expression) (stmts []ast.Stmt, locals map[string]string) {
+	locals = map[string]string{}
+	used := map[string]bool{}
+	withContext := services.usesContext(serviceName, map[string]bool{})
+
+	if service.Decorates != "" && service.usesInner() &&
+		services.isFallible(services.innerName(serviceName), map[string]bool{}) {
+		locals[InnerReference] = "depInner"
+		stmts = append(stmts,
+			&ast.AssignStmt{
+				Tok: token.DEFINE,
+				Lhs: []ast.Expr{newIdent("depInner"), newIdent("err")},
+				Rhs: []ast.Expr{newIdent(services.innerCall(serviceName, withContext))},
+			},
+			&ast.IfStmt{
+				Cond: newIdent("err != nil"),
+				Body: newBlock(service.astReturnError(file, serviceName)),
+			},
+		)
 	}
 
-	return &ast.FuncType{
-		Params:  service.astAllArguments(services),
-		Results: newFieldList(string(ty)),
+	for _, expr := range expressions {
+		deps := services.expressionDependencies(expr)
+		for _, ref := range expr.TagReferences() {
+			tag, _, _ := parseTagReference(ref)
+			deps = append(deps, services.TaggedServiceNames(tag)...)
+		}
+
+		for _, dep := range deps {
+			depName := strings.Split(dep, "(")[0]
+			if _, ok := locals[dep]; ok || depName == serviceName ||
+				!services.IsFallible(depName) {
+				continue
+			}
+
+			// The same prototype can be used with different arguments.
+			local := "dep" + depName
+			for i := 2; used[local]; i++ {
+				local = fmt.Sprintf("dep%s%d", depName, i)
+			}
+			used[local] = true
+			locals[dep] = local
+
+			stmts = append(stmts,
+				&ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent(local), newIdent("err")},
+					Rhs: []ast.Expr{newIdent(services.getterCall(dep, withContext))},
+				},
+				&ast.IfStmt{
+					Cond: newIdent("err != nil"),
+					Body: newBlock(service.astReturnError(file, serviceName)),
+				},
+			)
+		}
 	}
+
+	return
 }
 
 func (service *Service) astFunctionBody(file *File, services Services, name, serviceName string) *ast.BlockStmt {
+	// A decorated service is created by its inner method, which does not
+	// include the decorators.
+	fallible := services.isFallible(serviceName, map[string]bool{})
+	withContext := services.usesContext(serviceName, map[string]bool{})
+
 	if name != "" && service.Scope == ScopePrototype {
+		var stmts []ast.Stmt
+		var locals map[string]string
+		if fallible {
+			stmts, locals = service.astResolveDependencies(file, services,
+				serviceName, []Expression{service.Returns})
+		}
+
+		stmts = append(stmts, service.astCheckParameters(file, serviceName)...)
+
+		envStmts, envLocals := service.astResolveEnv(file, serviceName)
+		stmts = append(stmts, envStmts...)
+
 		var arguments []string
-		for _, dep := range service.Returns.Dependencies() {
-			if dep[len(dep)-1:]!=")" {
-				dep = dep+"()"
+		if service.usesContext() {
+			arguments = append(arguments, ContextReference)
+		}
+
+		withContext := services.UsesContext(serviceName)
+		for _, dep := range services.expressionDependencies(service.Returns) {
+			if local, ok := locals[dep]; ok {
+				arguments = append(arguments, local)
+				continue
 			}
-			arguments = append(arguments, fmt.Sprintf("container.Get%s", dep))
+
+			arguments = append(arguments, services.getterCall(dep, withContext))
+		}
+		for _, ref := range service.Returns.ArgumentReferences() {
+			arguments = append(arguments, services.argumentExpression(ref, locals, withContext))
+		}
+		for _, ref := range service.envReferences() {
+			arguments = append(arguments, envLocals[envLocalKey(ref)])
+		}
+		for _, name := range service.parameterReferences() {
+			arguments = append(arguments, "container.Parameters."+parameterFieldName(name))
 		}
 		arguments = append(arguments, service.Arguments.Names()...)
 
-		return newBlock(
-			newReturn(newIdent("container." + serviceName + "(" + strings.Join(arguments, ", ") + ")")),
-		)
+		call := newIdent("container." + serviceName + "(" + strings.Join(arguments, ", ") + ")")
+
+		switch {
+		case service.ReturnsError:
+			stmts = append(stmts,
+				&ast.AssignStmt{
+					Tok: token.DEFINE,
+					Lhs: []ast.Expr{newIdent("service"), newIdent("err")},
+					Rhs: []ast.Expr{call},
+				},
+				&ast.IfStmt{
+					Cond: newIdent("err != nil"),
+					Body: newBlock(service.astReturnError(file, serviceName)),
+				},
+				newReturn(newIdent("service"), newIdent("nil")),
+			)
+
+		case fallible:
+			stmts = append(stmts, newReturn(call, newIdent("nil")))
+
+		default:
+			stmts = append(stmts, newReturn(call))
+		}
+
+		return newBlock(stmts...)
 	}
 
 	var stmts, instantiation []ast.Stmt
 	serviceVariable := "container." + name
 	serviceTempVariable := "service"
 
+	// Dependencies that return errors are only resolved by the getter. The
+	// prototype functions receive them as arguments.
+	var locals map[string]string
+	if name != "" && fallible {
+		instantiation, locals = service.astResolveDependencies(file, services,
+			serviceName, append(service.Expressions(), service.OnInit...))
+	}
+
+	if locals == nil {
+		locals = map[string]string{}
+	}
+
+	if name != "" {
+		instantiation = append(instantiation,
+			service.astCheckParameters(file, serviceName)...)
+
+		envStmts, envLocals := service.astResolveEnv(file, serviceName)
+		instantiation = append(instantiation, envStmts...)
+		for ref, local := range envLocals {
+			locals[ref] = local
+		}
+	}
+
+	// The getter receives the context as ctx, as does the prototype function
+	// when the service uses it.
+	if (name != "" && withContext) || (name == "" && service.usesContext()) {
+		locals[ContextReference] = ContextReference
+	}
+
+	if _, ok := locals[InnerReference]; !ok && service.Decorates != "" {
+		locals[InnerReference] = services.innerCall(serviceName, withContext)
+	}
+
 	// Instantiation
 	if service.Returns == "" {
-		instantiation = []ast.Stmt{
-			&ast.AssignStmt{
-				Tok: token.DEFINE,
-				Lhs: []ast.Expr{newIdent(serviceTempVariable)},
-				Rhs: []ast.Expr{
-					&ast.CompositeLit{
-						Type: newIdent(service.Type.CreateLocalEntityType()),
-					},
+		instantiation = append(instantiation, &ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent(serviceTempVariable)},
+			Rhs: []ast.Expr{
+				&ast.CompositeLit{
+					Type: newIdent(service.Type.CreateLocalEntityType()),
 				},
 			},
-		}
+		})
 	} else {
 		lhs := []ast.Expr{newIdent(serviceTempVariable)}
 
-		if service.Error != "" {
+		if service.Error != "" || service.ReturnsError {
 			lhs = append(lhs, newIdent("err"))
 		}
 
-		instantiation = []ast.Stmt{
-			&ast.AssignStmt{
-				Tok: token.DEFINE,
-				Lhs: lhs,
-				Rhs: []ast.Expr{
-					newIdent(service.Returns.performSubstitutions(file, services, name == "")),
-				},
+		instantiation = append(instantiation, &ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: lhs,
+			Rhs: []ast.Expr{
+				newIdent(service.Returns.performSubstitutions(file, services, name == "", locals)),
 			},
-		}
+		})
+
+		if service.ReturnsError {
+			// The prototype function returns the error as is, it is
+			// wrapped by the getter.
+			returnErr := service.astReturnError(file, serviceName)
+			if name == "" {
+				returnErr = newReturn(newIdent(service.ZeroValue()), newIdent("err"))
+			}
 
-		if service.Error != "" {
+			instantiation = append(instantiation, &ast.IfStmt{
+				Cond: newIdent("err != nil"),
+				Body: newBlock(returnErr),
+			})
+		} else if service.Error != "" {
 			instantiation = append(instantiation, &ast.IfStmt{
 				Cond: newIdent("err != nil"),
 				Body: &ast.BlockStmt{
@@ -247,13 +821,36 @@ func (service *Service) astFunctionBody(file *File, services Services, name, ser
 		instantiation = append(instantiation, &ast.AssignStmt{
 			Tok: token.ASSIGN,
 			Lhs: []ast.Expr{&ast.Ident{Name: serviceTempVariable + "." + property.Name}},
-			Rhs: []ast.Expr{&ast.Ident{Name: property.Value.performSubstitutions(file, services, name == "")}},
+			Rhs: []ast.Expr{&ast.Ident{Name: property.Value.performSubstitutions(file, services, name == "", locals)}},
 		})
 	}
 
+	// Hooks
+	if len(service.OnInit) > 0 {
+		hookLocals := map[string]string{serviceName: serviceTempVariable}
+		for dep, local := range locals {
+			hookLocals[dep] = local
+		}
+
+		for _, hook := range service.OnInit {
+			instantiation = append(instantiation, &ast.ExprStmt{
+				X: newIdent(hook.performSubstitutions(file, services, name == "", hookLocals)),
+			})
+		}
+	}
+
 	// Scope
 	switch service.Scope {
-	case ScopeNotSet, ScopeContainer:
+	case ScopeNotSet, ScopeContainer, ScopeRequest:
+		if service.Scope != ScopeRequest {
+			stmts = append(stmts, service.astParentGetter(services, serviceName))
+		}
+
+		if service.Close != CloseDisabled {
+			instantiation = append(instantiation,
+				service.astCloser(file, services, serviceName))
+		}
+
 		if service.Type.IsPointer() || service.Interface != "" {
 			instantiation = append(instantiation, &ast.AssignStmt{
 				Tok: token.ASSIGN,
@@ -268,6 +865,16 @@ func (service *Service) astFunctionBody(file *File, services Services, name, ser
 			})
 		}
 
+		if service.IsThreadSafe(file) {
+			astutil.AddImport(file.fset, file.file, "sync")
+
+			mutex := "container." + mutexFieldName(name)
+			stmts = append(stmts,
+				&ast.ExprStmt{X: newIdent(mutex + ".Lock()")},
+				&ast.DeferStmt{Call: &ast.CallExpr{Fun: newIdent(mutex + ".Unlock")}},
+			)
+		}
+
 		stmts = append(stmts, &ast.IfStmt{
 			Cond: &ast.Ident{Name: serviceVariable + " == nil"},
 			Body: &ast.BlockStmt{
@@ -276,16 +883,49 @@ func (service *Service) astFunctionBody(file *File, services Services, name, ser
 		})
 
 		// Returns
-		if service.Type.IsPointer() || service.Interface != "" {
-			stmts = append(stmts, newReturn(newIdent(serviceVariable)))
+		result := serviceVariable
+		if !service.Type.IsPointer() && service.Interface == "" {
+			result = "*" + serviceVariable
+		}
+
+		if fallible {
+			stmts = append(stmts, newReturn(newIdent(result), newIdent("nil")))
 		} else {
-			stmts = append(stmts, newReturn(newIdent("*"+serviceVariable)))
+			stmts = append(stmts, newReturn(newIdent(result)))
 		}
 
 	case ScopePrototype:
 		stmts = append(stmts, instantiation...)
-		stmts = append(stmts, newReturn(newIdent("service")))
+		if service.ReturnsError {
+			stmts = append(stmts, newReturn(newIdent("service"), newIdent("nil")))
+		} else {
+			stmts = append(stmts, newReturn(newIdent("service")))
+		}
 	}
 
 	return newBlock(stmts...)
 }
+
+// astMustFunctionBody calls the getter of a service that returns an error and
+// panics with the error. withContext calls the getter that takes a context.
+func (service *Service) astMustFunctionBody(serviceName string, withContext bool) *ast.BlockStmt {
+	getter, args := "Get"+serviceName, service.Arguments.Names()
+	if withContext {
+		getter += "Context"
+		args = append([]string{ContextReference}, args...)
+	}
+
+	return newBlock(
+		&ast.AssignStmt{
+			Tok: token.DEFINE,
+			Lhs: []ast.Expr{newIdent("service"), newIdent("err")},
+			Rhs: []ast.Expr{newIdent("container." + getter +
+				"(" + strings.Join(args, ", ") + ")")},
+		},
+		&ast.IfStmt{
+			Cond: newIdent("err != nil"),
+			Body: newBlock(&ast.ExprStmt{X: newIdent("panic(err)")}),
+		},
+		newReturn(newIdent("service")),
+	)
+}
@@ -27,12 +27,126 @@ var serviceValidationTests = map[string]struct {
 		},
 		err: nil,
 	},
+	"scope_request": {
+		service: &Service{
+			Scope: "request",
+		},
+		err: nil,
+	},
 	"scope_invalid": {
 		service: &Service{
 			Scope: "foo",
 		},
 		err: errors.New("invalid scope: foo"),
 	},
+	"close_prototype": {
+		service: &Service{
+			Scope: ScopePrototype,
+			Close: "@{A}.Close()",
+		},
+		err: errors.New("close cannot be used with prototype scope"),
+	},
+	"eager": {
+		service: &Service{
+			Eager: true,
+		},
+		err: nil,
+	},
+	"eager_prototype": {
+		service: &Service{
+			Scope: ScopePrototype,
+			Eager: true,
+		},
+		err: errors.New("eager cannot be used with prototype scope"),
+	},
+	"eager_request": {
+		service: &Service{
+			Scope: ScopeRequest,
+			Eager: true,
+		},
+		err: errors.New("eager cannot be used with request scope"),
+	},
+	"eager_arguments": {
+		service: &Service{
+			Arguments: Arguments{"i": "int"},
+			Eager:     true,
+		},
+		err: errors.New("eager cannot be used with arguments"),
+	},
+	"close_context": {
+		service: &Service{
+			Close: "@{A}.Shutdown(@{ctx})",
+		},
+		err: errors.New("close cannot use @{ctx}"),
+	},
+	"tags_arguments": {
+		service: &Service{
+			Arguments: Arguments{"i": "int"},
+			Tags:      []string{"foo"},
+		},
+		err: errors.New("tags cannot be used with arguments"),
+	},
+	"decorates_prototype": {
+		service: &Service{
+			Scope:     ScopePrototype,
+			Decorates: "A",
+		},
+		err: errors.New("decorates cannot be used with prototype scope"),
+	},
+	"decorates_arguments": {
+		service: &Service{
+			Arguments: Arguments{"i": "int"},
+			Decorates: "A",
+		},
+		err: errors.New("decorates cannot be used with arguments"),
+	},
+	"inner_without_decorates": {
+		service: &Service{
+			Returns: "NewA(@{inner})",
+		},
+		err: errors.New("@{inner} can only be used with decorates"),
+	},
+	"alias": {
+		service: &Service{
+			Alias:      "A",
+			Deprecated: "Use GetA instead.",
+		},
+		err: nil,
+	},
+	"alias_with_type": {
+		service: &Service{
+			Alias: "A",
+			Type:  "*A",
+		},
+		err: errors.New("alias cannot be used with type"),
+	},
+	"alias_with_thread_safe": {
+		service: &Service{
+			Alias:      "A",
+			ThreadSafe: new(bool),
+		},
+		err: errors.New("alias cannot be used with thread_safe"),
+	},
+	"deprecated_without_alias": {
+		service: &Service{
+			Type:       "*A",
+			Deprecated: "Use GetA instead.",
+		},
+		err: errors.New("deprecated can only be used with alias"),
+	},
+	"error_with_returns": {
+		service: &Service{
+			Returns: "NewFoo()",
+			Error:   "panic(err)",
+		},
+		err: nil,
+	},
+	"error_without_returns": {
+		service: &Service{
+			Error: "panic(err)",
+		},
+		err: errors.New("error cannot be used without returns"),
+	},
 }
 
 func TestService_Validate(t *testing.T) {
@@ -268,3 +382,90 @@ func TestService_ContainerFieldType(t *testing.T) {
 		})
 	}
 }
+
+func TestService_IsThreadSafe(t *testing.T) {
+	yes, no := true, false
+
+	for testName, test := range map[string]struct {
+		fileThreadSafe bool
+		service        *Service
+		isThreadSafe   bool
+	}{
+		"Default": {
+			service:      &Service{},
+			isThreadSafe: false,
+		},
+		"File": {
+			fileThreadSafe: true,
+			service:        &Service{},
+			isThreadSafe:   true,
+		},
+		"Service": {
+			service:      &Service{ThreadSafe: &yes},
+			isThreadSafe: true,
+		},
+		"ServiceOverridesFile": {
+			fileThreadSafe: true,
+			service:        &Service{ThreadSafe: &no},
+			isThreadSafe:   false,
+		},
+		"Request": {
+			fileThreadSafe: true,
+			service:        &Service{Scope: ScopeRequest},
+			isThreadSafe:   true,
+		},
+		"Prototype": {
+			fileThreadSafe: true,
+			service:        &Service{Scope: ScopePrototype, ThreadSafe: &yes},
+			isThreadSafe:   false,
+		},
+		"Arguments": {
+			fileThreadSafe: true,
+			service:        &Service{Arguments: Arguments{"foo": "int"}},
+			isThreadSafe:   false,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			file := &File{ThreadSafe: test.fileThreadSafe}
+			assert.Equal(t, test.isThreadSafe, test.service.IsThreadSafe(file))
+		})
+	}
+}
+
+func TestGenerateContainer_DependencyLocals(t *testing.T) {
+	source := generateSource(t, `services:
+  A:
+    type: '*A'
+    returns: NewA()
+    returns_error: true
+  P:
+    type: '*P'
+    scope: prototype
+    arguments:
+      name: string
+    returns: NewP(name)
+    returns_error: true
+  B:
+    type: '*B'
+    returns: NewB(@{A}, @{P("x")}, @{P("y")})
+`)
+
+	// Each name only depends on the service it is for.
+	assert.Contains(t, source, "depA, err := container.GetA()\n")
+	assert.Contains(t, source, `depP, err := container.GetP("x")`+"\n")
+	assert.Contains(t, source, `depP2, err := container.GetP("y")`+"\n")
+	assert.Contains(t, source, "service := NewB(depA, depP, depP2)\n")
+}
+
+func TestService_nonZeroKeysExcept(t *testing.T) {
+	service := &Service{
+		Type:      "*A",
+		Alias:     "B",
+		Returns:   "NewA()",
+		Interface: "A",
+	}
+
+	assert.Equal(t, []string{"interface", "returns"},
+		service.nonZeroKeysExcept("type", "alias"))
+	assert.Nil(t, service.nonZeroKeysExcept("type", "alias", "returns", "interface"))
+}
@@ -4,6 +4,7 @@ import (
 	"go/ast"
 	"go/token"
 	"sort"
+	"strings"
 )
 
 type Services map[string]*Service
@@ -32,12 +33,193 @@ func (services Services) ServicesWithScope(scope string) Services {
 	return ss
 }
 
+// Dependencies returns the names of the services that are created when
+// serviceName is created. Services that are injected as a function (such as
+// prototypes) are not included because they are not created until the function
+// is called.
+func (services Services) Dependencies(serviceName string) []string {
+	service := services[serviceName]
+	if service == nil {
+		return nil
+	}
+
+	// The on_init hooks run while the service is being created, but they
+	// refer to the service itself as the new instance.
+	expressions := append(service.Expressions(), service.OnInit...)
+
+	var deps []string
+	for i, expr := range expressions {
+		for _, dep := range services.expressionDependencies(expr) {
+			depName := strings.Split(dep, "(")[0]
+			depService := services[depName]
+			if depService == nil {
+				continue
+			}
+
+			if i >= len(expressions)-len(service.OnInit) && depName == serviceName {
+				continue
+			}
+
+			_, isFunc := depService.ContainerFieldType(services).(*ast.FuncType)
+			if isFunc && !strings.Contains(dep, "(") {
+				continue
+			}
+
+			deps = append(deps, services.Decorated(depName))
+		}
+
+		// All of the tagged services are created, including prototypes.
+		for _, ref := range expr.TagReferences() {
+			tag, _, _ := parseTagReference(ref)
+			for _, dep := range services.TaggedServiceNames(tag) {
+				deps = append(deps, services.Decorated(dep))
+			}
+		}
+	}
+
+	if service.Decorates != "" && service.usesInner() && services[service.Decorates] != nil {
+		deps = append(deps, services.innerName(serviceName))
+	}
+
+	if services[service.Alias] != nil {
+		deps = append(deps, services.Decorated(service.Alias))
+	}
+
+	for _, condition := range service.When {
+		if services[condition.Use] != nil {
+			deps = append(deps, services.Decorated(condition.Use))
+		}
+	}
+
+	return uniqueSorted(deps)
+}
+
+// DependencyOrder returns all of the service names sorted so that each service
+// comes after the services it depends on. Services that do not depend on each
+// other are sorted by name.
+func (services Services) DependencyOrder() (serviceNames []string) {
+	added := map[string]bool{}
+
+	var add func(serviceName string)
+	add = func(serviceName string) {
+		if added[serviceName] {
+			return
+		}
+
+		// Cycles are reported by Validate, they must not recurse forever.
+		added[serviceName] = true
+		for _, dep := range services.Dependencies(serviceName) {
+			add(dep)
+		}
+
+		serviceNames = append(serviceNames, serviceName)
+	}
+
+	for _, serviceName := range services.ServiceNames() {
+		add(serviceName)
+	}
+
+	return
+}
+
+// IsFallible returns true if the getter for the service returns an error. This
+// is the case when the service itself returns an error, or when any service it
+// depends on does. The getter of a decorated service returns the outermost
+// decorator.
+func (services Services) IsFallible(serviceName string) bool {
+	return services.isFallible(services.Decorated(serviceName), map[string]bool{})
+}
+
+func (services Services) isFallible(serviceName string, seen map[string]bool) bool {
+	service := services[serviceName]
+	if service == nil || seen[serviceName] {
+		return false
+	}
+
+	if service.ReturnsError || service.envCanFail() || service.parametersCanFail() {
+		return true
+	}
+
+	seen[serviceName] = true
+	for _, dep := range services.Dependencies(serviceName) {
+		if services.isFallible(dep, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Cycles returns each of the dependency cycles between services. The first and
+// last name of each cycle is the same service.
+func (services Services) Cycles() (cycles [][]string) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[string]int{}
+	var path []string
+
+	var visit func(serviceName string)
+	visit = func(serviceName string) {
+		state[serviceName] = visiting
+		path = append(path, serviceName)
+
+		for _, dep := range services.Dependencies(serviceName) {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+
+			case visiting:
+				for i, name := range path {
+					if name == dep {
+						cycle := append([]string{}, path[i:]...)
+						cycles = append(cycles, append(cycle, dep))
+					}
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[serviceName] = visited
+	}
+
+	for _, serviceName := range services.ServiceNames() {
+		if state[serviceName] == unvisited {
+			visit(serviceName)
+		}
+	}
+
+	return
+}
+
+// mutexFieldName is the unexported Container field that guards the creation of
+// a thread safe service.
+func mutexFieldName(serviceName string) string {
+	return "mutex" + serviceName
+}
+
 // astContainer creates the Container struct.
-func (services Services) astContainerStruct() *ast.GenDecl {
+func (services Services) astContainerStruct(file *File) *ast.GenDecl {
 	var containerFields []*ast.Field
+	if len(file.Parameters) > 0 {
+		containerFields = append(containerFields, &ast.Field{
+			Names: []*ast.Ident{{Name: "Parameters"}},
+			Type:  newIdent("Parameters"),
+		})
+	}
+
 	for _, serviceName := range services.ServiceNames() {
 		service := services[serviceName]
 
+		// The getter of an alias, or a service with when, uses the field of the
+		// service it refers to.
+		if service.isReference() {
+			continue
+		}
+
 		containerFields = append(containerFields, &ast.Field{
 			Names: []*ast.Ident{
 				{Name: serviceName},
@@ -46,6 +228,45 @@ func (services Services) astContainerStruct() *ast.GenDecl {
 		})
 	}
 
+	// A mutex for each thread safe service is used, rather than sync.Once,
+	// so that services can still be replaced on the Container.
+	for _, serviceName := range services.ServiceNames() {
+		if services[serviceName].IsThreadSafe(file) {
+			containerFields = append(containerFields, &ast.Field{
+				Names: []*ast.Ident{
+					{Name: mutexFieldName(serviceName)},
+				},
+				Type: newIdent("sync.Mutex"),
+			})
+		}
+	}
+
+	// Services that need to be closed, in the order they were created.
+	containerFields = append(containerFields,
+		&ast.Field{
+			Names: []*ast.Ident{{Name: "closers"}},
+			Type:  newIdent("[]func() error"),
+		},
+		&ast.Field{
+			Names: []*ast.Ident{{Name: "closersMutex"}},
+			Type:  newIdent("sync.Mutex"),
+		},
+
+		// The container that created this one with NewScope.
+		&ast.Field{
+			Names: []*ast.Ident{{Name: "parent"}},
+			Type:  newIdent("*Container"),
+		},
+	)
+
+	// The error of each parameter, returned by the getters that use it.
+	if len(file.Parameters) > 0 {
+		containerFields = append(containerFields, &ast.Field{
+			Names: []*ast.Ident{{Name: "parameterErrors"}},
+			Type:  newIdent("map[string]error"),
+		})
+	}
+
 	return &ast.GenDecl{
 		Tok: token.TYPE,
 		Specs: []ast.Spec{
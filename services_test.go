@@ -0,0 +1,192 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServices_Dependencies(t *testing.T) {
+	services := Services{
+		"A": {
+			Type:    "*A",
+			Returns: "NewA(@{B}, @{C}, @{Missing})",
+			Properties: map[string]Expression{
+				"D": "@{D}",
+			},
+			Error: "panic(@{E})",
+		},
+		"B": {Type: "*B"},
+		"C": {Type: "*C"},
+		"D": {Type: "*D"},
+		"E": {Type: "*E"},
+
+		// A prototype is injected as a function, unless it is called.
+		"F": {Type: "*F", Returns: "NewF(@{P}, @{Q(1)})"},
+		"P": {Type: "*P", Scope: ScopePrototype},
+		"Q": {Type: "*Q", Arguments: Arguments{"i": "int"}},
+
+		// An on_init hook refers to the service itself as the new instance.
+		"G": {Type: "*G", OnInit: []Expression{"@{G}.Register(@{B})"}},
+	}
+
+	assert.Equal(t, []string{"B", "C", "D", "E"}, services.Dependencies("A"))
+	assert.Equal(t, []string{"Q"}, services.Dependencies("F"))
+	assert.Equal(t, []string{"B"}, services.Dependencies("G"))
+	assert.Nil(t, services.Dependencies("B"))
+}
+
+func TestServices_Cycles(t *testing.T) {
+	for testName, test := range map[string]struct {
+		services Services
+		cycles   [][]string
+	}{
+		"None": {
+			services: Services{
+				"A": {Returns: "NewA(@{B})"},
+				"B": {Returns: "NewB(@{C})"},
+				"C": {},
+			},
+			cycles: nil,
+		},
+		"Self": {
+			services: Services{
+				"A": {Returns: "NewA(@{A})"},
+			},
+			cycles: [][]string{{"A", "A"}},
+		},
+		"Two": {
+			services: Services{
+				"A": {Returns: "NewA(@{B})"},
+				"B": {Properties: map[string]Expression{"A": "@{A}"}},
+			},
+			cycles: [][]string{{"A", "B", "A"}},
+		},
+		"Three": {
+			services: Services{
+				"A": {Returns: "NewA(@{B})"},
+				"B": {Returns: "NewB(@{C})"},
+				"C": {Returns: "NewC(@{A})"},
+				"D": {Returns: "NewD(@{A})"},
+			},
+			cycles: [][]string{{"A", "B", "C", "A"}},
+		},
+		"ThroughPrototypeFunction": {
+			services: Services{
+				"A": {Returns: "NewA(@{B})"},
+				"B": {Scope: ScopePrototype, Returns: "NewB(@{A})"},
+			},
+			cycles: nil,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.cycles, test.services.Cycles())
+		})
+	}
+}
+
+func TestServices_DependencyOrder(t *testing.T) {
+	services := Services{
+		"A": {Returns: "NewA(@{C})"},
+		"B": {},
+		"C": {Properties: map[string]Expression{"D": "@{D}"}},
+		"D": {},
+		"E": {OnInit: []Expression{"@{E}.Use(@{A})"}},
+	}
+
+	assert.Equal(t, []string{"D", "C", "A", "B", "E"}, services.DependencyOrder())
+}
+
+func TestServices_IsFallible(t *testing.T) {
+	services := Services{
+		"A": {Returns: "NewA()", ReturnsError: true},
+		"B": {Returns: "NewB(@{A})"},
+		"C": {Properties: map[string]Expression{"B": "@{B}"}},
+		"D": {Returns: "NewD()"},
+
+		// Prototypes are injected as functions so their errors are not
+		// returned when the service is created.
+		"E": {Returns: "NewE()", ReturnsError: true, Scope: ScopePrototype},
+		"F": {Returns: "NewF(@{E})"},
+		"G": {Returns: "NewG(@{E()})"},
+	}
+
+	for serviceName, isFallible := range map[string]bool{
+		"A": true,
+		"B": true,
+		"C": true,
+		"D": false,
+		"E": true,
+		"F": false,
+		"G": true,
+	} {
+		t.Run(serviceName, func(t *testing.T) {
+			assert.Equal(t, isFallible, services.IsFallible(serviceName))
+		})
+	}
+}
+
+func TestServices_UsesContext(t *testing.T) {
+	services := Services{
+		"A": {Returns: "NewA(@{ctx})"},
+		"B": {Returns: "NewB(@{A})"},
+		"C": {Properties: map[string]Expression{"B": "@{B}"}},
+		"D": {Returns: "NewD()"},
+		"E": {OnInit: []Expression{"@{E}.Start(@{ctx})"}},
+
+		// Prototypes are injected as functions so they receive the context
+		// when they are called.
+		"F": {Returns: "NewF(@{ctx})", Scope: ScopePrototype},
+		"G": {Returns: "NewG(@{F})"},
+		"H": {Returns: "NewH(@{F()})"},
+	}
+
+	for serviceName, usesContext := range map[string]bool{
+		"A": true,
+		"B": true,
+		"C": true,
+		"D": false,
+		"E": true,
+		"F": true,
+		"G": false,
+		"H": true,
+	} {
+		t.Run(serviceName, func(t *testing.T) {
+			assert.Equal(t, usesContext, services.UsesContext(serviceName))
+		})
+	}
+}
+
+func TestServices_getterCall(t *testing.T) {
+	services := Services{
+		"A": {Returns: "NewA(@{ctx})"},
+		"B": {Returns: "NewB()"},
+		"C": {Returns: "NewC(@{ctx}, i)", Arguments: Arguments{"i": "int"}},
+	}
+
+	for ref, expected := range map[string][2]string{
+		"A":    {"container.GetA()", "container.GetAContext(ctx)"},
+		"B":    {"container.GetB()", "container.GetB()"},
+		"C(1)": {"container.GetC(1)", "container.GetCContext(ctx, 1)"},
+	} {
+		t.Run(ref, func(t *testing.T) {
+			assert.Equal(t, expected[0], services.getterCall(ref, false))
+			assert.Equal(t, expected[1], services.getterCall(ref, true))
+		})
+	}
+}
+
+func TestServices_CapturedRequestServices(t *testing.T) {
+	services := Services{
+		"A": {Returns: "NewA(@{R}, @{P()}, @{Q}, @{C})"},
+		"C": {Returns: "NewC(@{S})"},
+		"P": {Scope: ScopePrototype, Returns: "NewP(@{S})"},
+		"Q": {Scope: ScopePrototype, Returns: "NewQ(@{T})"},
+		"R": {Scope: ScopeRequest},
+		"S": {Scope: ScopeRequest},
+		"T": {Scope: ScopeRequest},
+	}
+
+	// C is checked on its own, and Q is not called when A is created.
+	assert.Equal(t, []string{"S", "R"}, services.CapturedRequestServices("A"))
+	assert.Equal(t, []string{"S"}, services.CapturedRequestServices("C"))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/elliotchance/pie/pie"
+)
+
+const (
+	// TaggedPrefix is used to inject every service with a tag as a slice, such
+	// as "@{tagged:health}".
+	TaggedPrefix = "tagged:"
+
+	// TaggedMapPrefix is used to inject every service with a tag as a map
+	// keyed by the service name, such as "@{tagged_map:health}".
+	TaggedMapPrefix = "tagged_map:"
+)
+
+// parseTagReference returns the tag name of a reference such as
+// "tagged:health". ok is false if the reference is not for a tag.
+func parseTagReference(ref string) (tag string, isMap, ok bool) {
+	switch {
+	case strings.HasPrefix(ref, TaggedPrefix):
+		return strings.TrimPrefix(ref, TaggedPrefix), false, true
+
+	case strings.HasPrefix(ref, TaggedMapPrefix):
+		return strings.TrimPrefix(ref, TaggedMapPrefix), true, true
+	}
+
+	return "", false, false
+}
+
+// TagReferences returns the references to tagged services, such as
+// "tagged:health". They are not included in Dependencies.
+func (e Expression) TagReferences() []string {
+	return e.findReferences(serviceReferenceRegexp, func(ref string) bool {
+		_, _, ok := parseTagReference(ref)
+
+		return ok
+	})
+}
+
+// TaggedServiceNames returns the services with the tag. Services with a higher
+// priority come first, otherwise they are sorted by name.
+func (services Services) TaggedServiceNames(tag string) (serviceNames []string) {
+	for _, serviceName := range services.ServiceNames() {
+		service := services[serviceName]
+		if service != nil && pie.Strings(service.Tags).Contains(tag) {
+			serviceNames = append(serviceNames, serviceName)
+		}
+	}
+
+	sort.SliceStable(serviceNames, func(i, j int) bool {
+		return services[serviceNames[i]].Priority > services[serviceNames[j]].Priority
+	})
+
+	return
+}
+
+// TagType returns the element type for the services with the tag. All of the
+// services must be the same type, or have the same interface.
+func (services Services) TagType(tag string) (string, error) {
+	serviceNames := services.TaggedServiceNames(tag)
+	if len(serviceNames) == 0 {
+		return "", fmt.Errorf("no services are tagged: %s", tag)
+	}
+
+	first := serviceNames[0]
+	ty := services[first].InterfaceOrLocalEntityType(services, false)
+	for _, serviceName := range serviceNames[1:] {
+		other := services[serviceName].InterfaceOrLocalEntityType(services, false)
+		if other != ty {
+			return "", fmt.Errorf("services tagged %s must have the same type or interface: %s is %s, %s is %s",
+				tag, first, ty, serviceName, other)
+		}
+	}
+
+	return ty, nil
+}
+
+// tagCollectionType is the Go type of a tag reference, such as
+// "[]HealthChecker".
+func (services Services) tagCollectionType(ref string) string {
+	tag, isMap, _ := parseTagReference(ref)
+	ty, _ := services.TagType(tag)
+
+	if isMap {
+		return "map[string]" + ty
+	}
+
+	return "[]" + ty
+}
+
+// tagArgumentName is the name of the prototype function argument that receives
+// the tagged services.
+func tagArgumentName(ref string) string {
+	tag, isMap, _ := parseTagReference(ref)
+	name := "tagged"
+	if isMap {
+		name = "taggedMap"
+	}
+
+	for _, part := range regexp.MustCompile(`[^a-zA-Z0-9]+`).Split(tag, -1) {
+		if part != "" {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return name
+}
+
+// tagExpression creates the slice or map literal for a tag reference. Services
+// in locals have already been resolved.
+func (services Services) tagExpression(ref string, locals map[string]string, withContext bool) string {
+	tag, isMap, _ := parseTagReference(ref)
+
+	var elements []string
+	for _, serviceName := range services.TaggedServiceNames(tag) {
+		value, ok := locals[serviceName]
+		if !ok {
+			value = services.getterCall(serviceName, withContext)
+		}
+
+		if isMap {
+			value = fmt.Sprintf("%q: %s", serviceName, value)
+		}
+
+		elements = append(elements, value)
+	}
+
+	return fmt.Sprintf("%s{%s}", services.tagCollectionType(ref),
+		strings.Join(elements, ", "))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServices_TaggedServiceNames(t *testing.T) {
+	services := Services{
+		"A": {Tags: []string{"foo"}},
+		"B": {Tags: []string{"foo", "bar"}, Priority: 10},
+		"C": {Tags: []string{"bar"}},
+		"D": {Tags: []string{"foo"}, Priority: -1},
+		"E": {Tags: []string{"foo"}},
+	}
+
+	assert.Equal(t, []string{"B", "A", "E", "D"}, services.TaggedServiceNames("foo"))
+	assert.Equal(t, []string{"B", "C"}, services.TaggedServiceNames("bar"))
+	assert.Nil(t, services.TaggedServiceNames("baz"))
+}
+
+func TestServices_TagType(t *testing.T) {
+	for testName, test := range map[string]struct {
+		services Services
+		ty       string
+		err      error
+	}{
+		"Interface": {
+			services: Services{
+				"A": {Type: "*A", Interface: "io.Reader", Tags: []string{"foo"}},
+				"B": {Type: "*B", Interface: "io.Reader", Tags: []string{"foo"}},
+			},
+			ty: "io.Reader",
+		},
+		"Type": {
+			services: Services{
+				"A": {Type: "*A", Tags: []string{"foo"}},
+				"B": {Type: "*A", Tags: []string{"foo"}},
+			},
+			ty: "*A",
+		},
+		"Different": {
+			services: Services{
+				"A": {Type: "*A", Tags: []string{"foo"}},
+				"B": {Type: "*B", Tags: []string{"foo"}},
+			},
+			err: errors.New("services tagged foo must have the same type or interface: A is *A, B is *B"),
+		},
+		"None": {
+			services: Services{},
+			err:      errors.New("no services are tagged: foo"),
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			ty, err := test.services.TagType("foo")
+			assert.Equal(t, test.ty, ty)
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
+
+func TestServices_Dependencies_Tagged(t *testing.T) {
+	services := Services{
+		"A": {Returns: "NewA(@{tagged:foo})"},
+		"B": {Tags: []string{"foo"}},
+		"C": {Tags: []string{"foo"}, Scope: ScopePrototype},
+		"D": {Properties: map[string]Expression{"M": "@{tagged_map:foo}"}},
+	}
+
+	assert.Equal(t, []string{"B", "C"}, services.Dependencies("A"))
+	assert.Equal(t, []string{"B", "C"}, services.Dependencies("D"))
+}
+
+func TestTagArgumentName(t *testing.T) {
+	assert.Equal(t, "taggedHealth", tagArgumentName("tagged:health"))
+	assert.Equal(t, "taggedMapHttpMiddleware", tagArgumentName("tagged_map:http-middleware"))
+}
+
+func TestExpression_TagReferences(t *testing.T) {
+	expr := Expression("NewP(@{tagged:y}, @{tagged_map:x}, @{A}, @{tagged:x}, @{tagged:y})")
+
+	assert.Equal(t, []string{"tagged:x", "tagged:y", "tagged_map:x"},
+		expr.TagReferences())
+}
+
+func TestGenerateContainer_TagReferences(t *testing.T) {
+	source := generateSource(t, `services:
+  A:
+    type: '*A'
+    tags: [x]
+  B:
+    type: '*B'
+    tags: [y]
+  P:
+    type: '*P'
+    scope: prototype
+    returns: NewP(@{tagged:y}, @{tagged:x})
+`)
+
+	// The field, the function and the getter must agree on the order.
+	assert.Contains(t, source, " func(taggedX []*A, taggedY []*B) *P\n")
+	assert.Contains(t, source, "P: func(taggedX []*A, taggedY []*B) *P {\n")
+	assert.Contains(t, source,
+		"return container.P([]*A{container.GetA()}, []*B{container.GetB()})\n")
+}
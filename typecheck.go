@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TypeCheck loads the package that the container will be written to, using
+// source in place of the output file, and returns Diagnostics for any errors in
+// the generated code. Each error is reported against the service and key that
+// caused it, rather than the generated code.
+//
+// Errors in other files of the package are ignored because they are not caused
+// by dingo.
+func (file *File) TypeCheck(outputFile string, source []byte) error {
+	outputFile, err := filepath.Abs(outputFile)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports |
+			packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir:        filepath.Dir(outputFile),
+		Overlay:    map[string][]byte{outputFile: source},
+		BuildFlags: []string{"-tags=" + strings.Join(file.buildTags(), ",")},
+	}, ".")
+	if err != nil {
+		return fmt.Errorf("type check: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	generated, err := parser.ParseFile(fset, outputFile, source, 0)
+	if err != nil {
+		return fmt.Errorf("type check: %v", err)
+	}
+
+	var diagnostics Diagnostics
+	for _, pkg := range pkgs {
+		for _, typeErr := range pkg.TypeErrors {
+			pos := typeErr.Fset.Position(typeErr.Pos)
+			if pos.Filename != outputFile {
+				continue
+			}
+
+			offset := fset.File(generated.Pos()).Pos(pos.Offset)
+			diagnostics = append(diagnostics,
+				file.typeErrorDiagnostic(generated, offset, pos, typeErr.Msg))
+		}
+	}
+
+	return diagnostics.Err()
+}
+
+// typeErrorDiagnostic finds the service and key that produced the generated
+// code at offset. If the code does not belong to a service, the location of the
+// generated code is used instead.
+func (file *File) typeErrorDiagnostic(generated *ast.File, offset token.Pos, pos token.Position, msg string) *Diagnostic {
+	path, _ := astutil.PathEnclosingInterval(generated, offset, offset)
+
+	for _, node := range path {
+		if name := file.parameterForNode(node); name != "" {
+			return &Diagnostic{
+				Pos: file.Parameters[name].Position(),
+				Err: fmt.Errorf("parameter %s: %s", name, msg),
+			}
+		}
+	}
+
+	serviceName, keys := "", []string(nil)
+	for _, node := range path {
+		if keys == nil {
+			keys = generatedKeys(node)
+		}
+
+		if name := file.serviceForNode(node); name != "" {
+			serviceName = name
+			break
+		}
+	}
+
+	service := file.Services[serviceName]
+	if service == nil {
+		return &Diagnostic{
+			Pos: pos,
+			Err: fmt.Errorf("generated code does not compile: %s", msg),
+		}
+	}
+
+	if keys != nil && keys[0] == "import" && len(service.Import) == 0 {
+		// The import must have come from the type or interface.
+		keys = nil
+	}
+
+	if keys == nil || keys[0] == "type" {
+		keys = []string{"type"}
+		if service.Interface != "" {
+			keys = []string{"interface"}
+		}
+	}
+
+	if keys[0] == "returns" && service.Returns == "" {
+		keys = []string{"type"}
+	}
+
+	return &Diagnostic{
+		Pos:     service.Position(keys...),
+		Service: serviceName,
+		Err:     fmt.Errorf("%s (%s:%d:%d)", msg, filepath.Base(pos.Filename), pos.Line, pos.Column),
+	}
+}
+
+// serviceForNode returns the service name if the node is the getter method,
+// Container field or NewContainer function for a service.
+func (file *File) serviceForNode(node ast.Node) string {
+	var name string
+
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		if n.Recv != nil {
+			name = strings.TrimPrefix(strings.TrimPrefix(n.Name.Name, "Must"), "Get")
+			name = strings.TrimPrefix(name, "inner")
+			if _, ok := file.Services[name]; !ok {
+				name = strings.TrimSuffix(name, "Context")
+			}
+		}
+
+	case *ast.Field:
+		if len(n.Names) > 0 {
+			name = n.Names[0].Name
+		}
+
+	case *ast.KeyValueExpr:
+		if ident, ok := n.Key.(*ast.Ident); ok {
+			name = ident.Name
+		}
+
+	case *ast.ImportSpec:
+		importPath, _ := strconv.Unquote(n.Path.Value)
+		for _, serviceName := range file.Services.ServiceNames() {
+			if _, ok := file.Services[serviceName].Imports()[importPath]; ok {
+				return serviceName
+			}
+		}
+	}
+
+	if _, ok := file.Services[name]; ok {
+		return name
+	}
+
+	return ""
+}
+
+// generatedKeys returns the YAML key that generates the code for node. It
+// returns nil if the node does not map directly to a key.
+func generatedKeys(node ast.Node) []string {
+	switch n := node.(type) {
+	case *ast.ImportSpec:
+		return []string{"import"}
+
+	case *ast.FuncType:
+		return []string{"arguments"}
+
+	case *ast.IfStmt:
+		return []string{"error"}
+
+	case *ast.ReturnStmt:
+		return []string{"type"}
+
+	case *ast.AssignStmt:
+		lhs, ok := n.Lhs[0].(*ast.SelectorExpr)
+		if !ok {
+			return []string{"returns"}
+		}
+
+		if ident, ok := lhs.X.(*ast.Ident); ok && ident.Name == "service" {
+			return []string{"properties", lhs.Sel.Name}
+		}
+
+		return []string{"type"}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeTypeCheckPackage creates a module for the package that the generated
+// container is type checked with.
+func writeTypeCheckPackage(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"),
+		[]byte("module typecheck\n\ngo 1.22\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.go"), []byte(`package typecheck
+
+type Foo struct {
+	Bar string
+}
+
+type Namer interface {
+	Name() string
+}
+`), 0644))
+
+	return dir
+}
+
+func TestFile_TypeCheck(t *testing.T) {
+	dir := writeTypeCheckPackage(t)
+	configPath := filepath.Join(dir, "dingo.yml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`services:
+  Foo:
+    type: '*Foo'
+    properties:
+      Bar: '"bar"'
+      Baz: '"baz"'
+  Named:
+    type: '*Foo'
+    interface: Namer
+  Number:
+    type: int
+    returns: '"one"'
+  Optional:
+    type: '*Foo'
+    properties:
+      Bar: '@{?Missing}'
+  OptionalWithType:
+    type: '*Foo'
+    properties:
+      Bar: '@{?Missing:string}'
+`), 0644))
+
+	file, err := ParseYAMLFile(configPath)
+	require.NoError(t, err)
+
+	outputFile := filepath.Join(dir, "dingo.go")
+	file, err = GenerateContainer(file, "typecheck", outputFile)
+	require.NoError(t, err)
+
+	source, err := file.Source()
+	require.NoError(t, err)
+
+	err = file.TypeCheck(outputFile, source)
+	require.IsType(t, Diagnostics{}, err)
+
+	var actual []string
+	for _, diagnostic := range err.(Diagnostics) {
+		actual = append(actual, diagnostic.Pos.String()+": "+diagnostic.Service)
+	}
+
+	assert.Equal(t, []string{
+		configPath + ":6:7: Foo",
+		configPath + ":9:5: Named",
+		configPath + ":11:5: Number",
+		configPath + ":16:7: Optional",
+	}, actual)
+}
+
+func TestFile_TypeCheckParameters(t *testing.T) {
+	dir := writeTypeCheckPackage(t)
+	configPath := filepath.Join(dir, "dingo.yml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`parameters:
+  retries:
+    type: int
+    value: '"three"'
+services:
+  Foo:
+    type: '*Foo'
+`), 0644))
+
+	file, err := ParseYAMLFile(configPath)
+	require.NoError(t, err)
+
+	outputFile := filepath.Join(dir, "dingo.go")
+	file, err = GenerateContainer(file, "typecheck", outputFile)
+	require.NoError(t, err)
+
+	source, err := file.Source()
+	require.NoError(t, err)
+
+	err = file.TypeCheck(outputFile, source)
+	require.IsType(t, Diagnostics{}, err)
+	require.Len(t, err.(Diagnostics), 1)
+	assert.EqualError(t, err.(Diagnostics)[0], configPath+
+		`:2:3: parameter retries: cannot use "three" (untyped string constant) as int value in assignment`)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"go/ast"
 	"sort"
+	"strings"
 )
 
 func newIdent(name string) *ast.Ident {
@@ -25,6 +27,20 @@ func newFieldList(values ...string) *ast.FieldList {
 	}
 }
 
+// newReceiver is the receiver for methods on the Container.
+func newReceiver() *ast.FieldList {
+	return &ast.FieldList{
+		List: []*ast.Field{
+			{
+				Names: []*ast.Ident{
+					newIdent("container"),
+				},
+				Type: newIdent("*Container"),
+			},
+		},
+	}
+}
+
 func newFunc(name string, params []string, returns []string, body *ast.BlockStmt) *ast.FuncDecl {
 	return &ast.FuncDecl{
 		Name: newIdent(name),
@@ -76,3 +92,68 @@ func newCompositeLit(ty string, m map[string]ast.Expr) *ast.CompositeLit {
 		Elts: exprs,
 	}
 }
+
+// levenshtein returns the minimum number of single character edits to change a
+// into b.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+// closestName returns the name that is the fewest edits from name. An empty
+// string is returned if none of the names are close enough to be a likely
+// typo.
+func closestName(name string, names []string) (closest string) {
+	// Allow roughly one edit for every three characters.
+	best := len(name)/3 + 2
+	for _, candidate := range names {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < best {
+			best, closest = distance, candidate
+		}
+	}
+
+	return
+}
+
+// unknownNameError is the error for a kind of name, such as a service, that
+// does not exist. It suggests the closest of names.
+func unknownNameError(kind, name string, names []string) error {
+	err := fmt.Errorf("%s does not exist: %s", kind, name)
+	if suggestion := closestName(name, names); suggestion != "" {
+		err = fmt.Errorf("%v (did you mean %s?)", err, suggestion)
+	}
+
+	return err
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, value := range values[1:] {
+		if value < m {
+			m = value
+		}
+	}
+
+	return m
+}
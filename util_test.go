@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"SendEmail", "SendEmail", 0},
+		{"SendEmial", "SendEmail", 2},
+		{"SendEmai", "SendEmail", 1},
+		{"kitten", "sitting", 3},
+	} {
+		t.Run(test.a+"_"+test.b, func(t *testing.T) {
+			assert.Equal(t, test.distance, levenshtein(test.a, test.b))
+		})
+	}
+}
+
+func TestClosestName(t *testing.T) {
+	names := []string{"Clock", "CustomerWelcome", "SendEmail"}
+
+	for name, expected := range map[string]string{
+		"SendEmial":      "SendEmail",
+		"sendemail":      "SendEmail",
+		"Clok":           "Clock",
+		"CustomerWelcom": "CustomerWelcome",
+		"Database":       "",
+		"X":              "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, closestName(name, names))
+		})
+	}
+}
+
+func TestUnknownNameError(t *testing.T) {
+	names := []string{"Clock", "SendEmail"}
+
+	assert.EqualError(t, unknownNameError("service", "SendEmial", names),
+		"service does not exist: SendEmial (did you mean SendEmail?)")
+	assert.EqualError(t, unknownNameError("service", "Database", names),
+		"service does not exist: Database")
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+
+	"github.com/elliotchance/pie/pie"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Condition is one of the alternatives of a service with when. The service
+// named by Use is injected if all of the conditions that are set are true.
+// An alternative without any conditions is always used.
+type Condition struct {
+	// Env is checked when the service is created. It is either "NAME=value"
+	// for the environment variable to have that value, or "NAME" for the
+	// environment variable to not be empty.
+	Env string
+
+	// Profile and Tag are checked when the container is generated. Tag is a
+	// Go build tag, the generated file has a build constraint for every tag
+	// that is used.
+	Profile string
+	Tag     string
+
+	Use string
+}
+
+// HasCondition returns true if any of the conditions are set.
+func (condition *Condition) HasCondition() bool {
+	return condition.Env != "" || condition.Profile != "" || condition.Tag != ""
+}
+
+// matches returns true if the conditions that are checked when the container is
+// generated are true.
+func (condition *Condition) matches(profile string, tags []string) bool {
+	return (condition.Profile == "" || condition.Profile == profile) &&
+		(condition.Tag == "" || pie.Strings(tags).Contains(condition.Tag))
+}
+
+// envExpression is the Go expression that checks Env.
+func (condition *Condition) envExpression() string {
+	parts := strings.SplitN(condition.Env, "=", 2)
+	if len(parts) == 1 {
+		return fmt.Sprintf("os.Getenv(%q) != \"\"", parts[0])
+	}
+
+	return fmt.Sprintf("os.Getenv(%q) == %q", parts[0], parts[1])
+}
+
+// ValidateWhen checks that a service with when only has the keys that apply to
+// it, and that one of the alternatives is always used.
+func (service *Service) ValidateWhen() error {
+	if len(service.When) == 0 {
+		return nil
+	}
+
+	if keys := service.nonZeroKeysExcept("when", "interface", "type", "import"); len(keys) > 0 {
+		return fmt.Errorf("when cannot be used with %s", keys[0])
+	}
+
+	for _, condition := range service.When {
+		if condition.Use == "" {
+			return fmt.Errorf("each alternative of when must have use")
+		}
+	}
+
+	if service.When[len(service.When)-1].HasCondition() {
+		return fmt.Errorf("the last alternative of when must not have a condition")
+	}
+
+	return nil
+}
+
+// whenDiagnostics returns a problem for each alternative that cannot be used.
+func (service *Service) whenDiagnostics(serviceName string, services Services) (diagnostics Diagnostics) {
+	for _, condition := range service.When {
+		var err error
+		use := services[condition.Use]
+		switch {
+		case use == nil:
+			err = unknownNameError("service", condition.Use, services.ServiceNames())
+
+		case len(use.Arguments) > 0:
+			err = fmt.Errorf("when cannot use a service that has arguments: %s",
+				condition.Use)
+		}
+
+		if err != nil {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Pos:     service.Position("when"),
+				Service: serviceName,
+				Err:     err,
+			})
+		}
+	}
+
+	return
+}
+
+// isReference returns true if the service does not have a definition of its
+// own. The getter of an alias or a service with when calls the getter of
+// another service.
+func (service *Service) isReference() bool {
+	return service.Alias != "" || len(service.When) > 0
+}
+
+// BuildConstraint returns the build constraint for the generated file, such as
+// "!prod && test". Each tag used by when, and each profile if the profiles are
+// used as build tags, is required if it was provided to the generator,
+// otherwise it is excluded. An empty string is returned if there are no tags.
+func (file *File) BuildConstraint() string {
+	tags := map[string]bool{}
+	if file.profileTags {
+		for _, profile := range append([]string{file.profile}, file.profiles...) {
+			if profile != "" {
+				tags[profile] = true
+			}
+		}
+	}
+
+	for _, service := range file.Services {
+		if service == nil {
+			continue
+		}
+
+		for _, condition := range service.When {
+			if condition.Tag != "" {
+				tags[condition.Tag] = true
+			}
+
+			if condition.Profile != "" && file.profileTags {
+				tags[condition.Profile] = true
+			}
+		}
+	}
+
+	var terms []string
+	for tag := range tags {
+		if pie.Strings(file.buildTags()).Contains(tag) {
+			terms = append(terms, tag)
+		} else {
+			terms = append(terms, "!"+tag)
+		}
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		return strings.TrimPrefix(terms[i], "!") < strings.TrimPrefix(terms[j], "!")
+	})
+
+	return strings.Join(terms, " && ")
+}
+
+// buildTags returns the tags that the generated file is built with.
+func (file *File) buildTags() []string {
+	if file.profileTags && file.profile != "" {
+		return append([]string{file.profile}, file.tags...)
+	}
+
+	return file.tags
+}
+
+// selectAlternatives removes the alternatives that cannot be used because their
+// profile or tag does not match, and any alternatives after the first one that
+// is always used.
+func (file *File) selectAlternatives() {
+	for _, service := range file.Services {
+		if service == nil || len(service.When) == 0 {
+			continue
+		}
+
+		var when []*Condition
+		for _, condition := range service.When {
+			if !condition.matches(file.profile, file.tags) {
+				continue
+			}
+
+			when = append(when, condition)
+			if condition.Env == "" {
+				break
+			}
+		}
+
+		service.When = when
+	}
+}
+
+// astWhenGetters creates the getters for a service with when. They check each
+// condition in order and call the getter of the first service that matches.
+func (file *File) astWhenGetters(serviceName string, results func(fallible bool) *ast.FieldList) (decls []ast.Decl) {
+	service := file.Services[serviceName]
+	fallible := file.Services.IsFallible(serviceName)
+
+	ret := func(use string) ast.Stmt {
+		call := newIdent(file.Services.getterCall(use, true))
+		if fallible && !file.Services.IsFallible(use) {
+			return newReturn(call, newIdent("nil"))
+		}
+
+		return newReturn(call)
+	}
+
+	body := newBlock()
+	for _, condition := range service.When {
+		if condition.Env == "" {
+			body.List = append(body.List, ret(condition.Use))
+			break
+		}
+
+		astutil.AddImport(file.fset, file.file, "os")
+		body.List = append(body.List, &ast.IfStmt{
+			Cond: newIdent(condition.envExpression()),
+			Body: newBlock(ret(condition.Use)),
+		})
+	}
+
+	decls = file.astGetter(service, "Get"+serviceName, results(fallible),
+		file.Services.UsesContext(serviceName), body)
+
+	if fallible {
+		decls = append(decls, file.astMustGetter(service, serviceName, results(false),
+			file.Services.UsesContext(serviceName))...)
+	}
+
+	return
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestService_ValidateWhen(t *testing.T) {
+	for testName, test := range map[string]struct {
+		service  *Service
+		expected string
+	}{
+		"NoWhen": {&Service{Type: "*A"}, ""},
+		"Default": {&Service{Interface: "A", When: []*Condition{
+			{Env: "APP_ENV=prod", Use: "B"},
+			{Use: "C"},
+		}}, ""},
+		"NoDefault": {&Service{Interface: "A", When: []*Condition{
+			{Env: "APP_ENV=prod", Use: "B"},
+		}}, "the last alternative of when must not have a condition"},
+		"NoUse": {&Service{Interface: "A", When: []*Condition{
+			{Tag: "prod"},
+			{Use: "C"},
+		}}, "each alternative of when must have use"},
+		"Returns": {&Service{Interface: "A", Returns: "NewA()", When: []*Condition{
+			{Use: "C"},
+		}}, "when cannot be used with returns"},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			err := test.service.ValidateWhen()
+			if test.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestService_whenDiagnostics(t *testing.T) {
+	services := Services{
+		"B": {Type: "*B"},
+		"C": {Type: "*C", Arguments: Arguments{"c": "int"}},
+		"A": {Interface: "A", When: []*Condition{
+			{Env: "A", Use: "Bb"},
+			{Env: "B", Use: "C"},
+			{Use: "B"},
+		}},
+	}
+
+	var actual []string
+	for _, diagnostic := range services["A"].whenDiagnostics("A", services) {
+		actual = append(actual, diagnostic.Err.Error())
+	}
+
+	assert.Equal(t, []string{
+		"service does not exist: Bb (did you mean B?)",
+		"when cannot use a service that has arguments: C",
+	}, actual)
+}
+
+func TestServices_Dependencies_When(t *testing.T) {
+	services := Services{
+		"A": {Interface: "A", When: []*Condition{
+			{Env: "APP_ENV=prod", Use: "B"},
+			{Use: "C"},
+		}},
+		"B": {Type: "*B"},
+		"C": {Type: "*C", Scope: ScopeRequest},
+		"D": {Returns: "NewD(@{A})"},
+	}
+
+	assert.Equal(t, []string{"B", "C"}, services.Dependencies("A"))
+	assert.Equal(t, []string{"C"}, services.CapturedRequestServices("D"))
+}
+
+func TestCondition_envExpression(t *testing.T) {
+	for env, expected := range map[string]string{
+		"APP_ENV=prod": `os.Getenv("APP_ENV") == "prod"`,
+		"APP_ENV=":     `os.Getenv("APP_ENV") == ""`,
+		"APP_ENV":      `os.Getenv("APP_ENV") != ""`,
+	} {
+		t.Run(env, func(t *testing.T) {
+			assert.Equal(t, expected, (&Condition{Env: env}).envExpression())
+		})
+	}
+}
+
+func TestFile_selectAlternatives(t *testing.T) {
+	for testName, test := range map[string]struct {
+		profile  string
+		tags     []string
+		expected []string
+	}{
+		"Default":    {"", nil, []string{"Env", "Default"}},
+		"Profile":    {"dev", nil, []string{"Env", "Dev"}},
+		"Tag":        {"", []string{"prod"}, []string{"Prod"}},
+		"ProfileTag": {"dev", []string{"prod"}, []string{"Prod"}},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			file := &File{
+				Services: Services{
+					"A": {Interface: "A", When: []*Condition{
+						{Tag: "prod", Use: "Prod"},
+						{Env: "APP_ENV=staging", Use: "Env"},
+						{Profile: "dev", Use: "Dev"},
+						{Use: "Default"},
+					}},
+				},
+				profile: test.profile,
+				tags:    test.tags,
+			}
+			file.selectAlternatives()
+
+			var actual []string
+			for _, condition := range file.Services["A"].When {
+				actual = append(actual, condition.Use)
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestFile_BuildConstraint(t *testing.T) {
+	for testName, test := range map[string]struct {
+		tags     []string
+		expected string
+	}{
+		"NoTags":   {nil, "!prod && !test"},
+		"Prod":     {[]string{"prod"}, "prod && !test"},
+		"ProdTest": {[]string{"test", "prod"}, "prod && test"},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			file := parseYAML(t, `services:
+  A:
+    interface: A
+    when:
+      - tag: test
+        use: B
+      - tag: prod
+        use: B
+      - use: B
+  B:
+    type: '*B'
+`)
+			file.tags = test.tags
+
+			assert.Equal(t, test.expected, file.BuildConstraint())
+
+			file, err := GenerateContainer(file, "main", "dingo.go")
+			require.NoError(t, err)
+
+			source, err := file.Source()
+			require.NoError(t, err)
+			assert.Contains(t, string(source), "//go:build "+test.expected+"\n")
+		})
+	}
+}
+
+func TestFile_BuildConstraintNoTags(t *testing.T) {
+	file := parseYAML(t, `services:
+  A:
+    type: '*A'
+`)
+
+	assert.Equal(t, "", file.BuildConstraint())
+}